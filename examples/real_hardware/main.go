@@ -98,7 +98,8 @@ func testConnection(brick *buildhat.Brick) {
 	devices := brick.GetDeviceInfo()
 	fmt.Printf("✅ Connection successful! Found %d ports\n", len(devices))
 
-	for port, info := range devices {
+	for _, port := range brick.Ports() {
+		info := devices[port]
 		status := "❌ Disconnected"
 		if info.Connected {
 			status = "✅ Connected"
@@ -119,7 +120,8 @@ func scanDevices(brick *buildhat.Brick, logger *slog.Logger) {
 	time.Sleep(2 * time.Second)
 
 	devices := brick.GetDeviceInfo()
-	for port, info := range devices {
+	for _, port := range brick.Ports() {
+		info := devices[port]
 		if info.Connected {
 			fmt.Printf("Port %s: %s (%s)\n", port, info.Name, info.Category)
 		} else {
@@ -183,12 +185,11 @@ func testMotorControl(brick *buildhat.Brick, logger *slog.Logger) {
 	fmt.Println("🎮 Testing motor control...")
 
 	// Check if we have any motors connected
-	devices := brick.GetDeviceInfo()
 	var motorPort buildhat.Port
 	var motorFound bool
 
-	for port, info := range devices {
-		if info.Connected && info.Category == buildhat.DeviceCategoryMotor {
+	for _, port := range brick.Ports() {
+		if brick.IsMotor(port) {
 			motorPort = port
 			motorFound = true
 			break
@@ -200,7 +201,7 @@ func testMotorControl(brick *buildhat.Brick, logger *slog.Logger) {
 		return
 	}
 
-	fmt.Printf("✅ Motor detected: %s on port %s\n", devices[motorPort].Name, motorPort)
+	fmt.Printf("✅ Motor detected: %s on port %s\n", brick.GetDeviceInfo()[motorPort].Name, motorPort)
 
 	// Create motor instance
 	motor := brick.Motor(motorPort)
@@ -2,16 +2,57 @@ package buildhat
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// subscriberBufferSize is the number of readings buffered per subscriber
+const subscriberBufferSize = 16
+
+// sensorHookBufferSize is the number of readings queued for the OnSensorData hook
+const sensorHookBufferSize = 64
+
+// portHookBufferSize is the number of connection changes queued for the OnPortChange hook
+const portHookBufferSize = 16
+
+// maxPendingEchoes bounds the commands remembered while waiting for their echo
+const maxPendingEchoes = 32
+
+// lineCollectorBufferSize is the number of lines buffered for a multi-line reply
+const lineCollectorBufferSize = 128
+
+// helpQuietPeriod ends a help reply that isn't followed by a prompt
+const helpQuietPeriod = 200 * time.Millisecond
+
+// replyTimeout bounds how long GetVoltage and GetHardwareVersion wait for their reply
+const replyTimeout = 5 * time.Second
+
+// maxConsecutiveReadErrors is how many read errors in a row, with no line received in between,
+// mark the connection as lost
+const maxConsecutiveReadErrors = 3
+
+// ErrBrickClosed is returned by operations on a brick after Close
+var ErrBrickClosed = errors.New("brick is closed")
+
+// ErrStopped is returned by movements interrupted by StopAll
+var ErrStopped = errors.New("motor stopped")
+
+// ErrSensorTimeout is returned by sensor reads that received no data in time
+var ErrSensorTimeout = errors.New("timeout waiting for sensor data")
+
+// underVoltageHysteresis is how far above the threshold, in volts, the input must recover
+// before an under-voltage alarm clears
+const underVoltageHysteresis = 0.2
+
 // Brick represents a BuildHat device
 type Brick struct {
 	input   io.Reader
@@ -24,15 +65,70 @@ type Brick struct {
 	mu      sync.RWMutex
 
 	// Connection state
-	connections    [NumPorts]*Connection
-	vinFutures     []chan float64
-	versionFutures []chan string
-	sensorFutures  [NumPorts][]chan []any // Sensor data futures per port
-	rampFutures    [NumPorts][]chan bool  // Ramp completion futures per port
-	pulseFutures   [NumPorts][]chan bool  // Pulse completion futures per port
+	connections   [NumPorts]*Connection
+	sensorFutures [NumPorts][]chan SensorReading // Sensor data futures per port
+	rampFutures   [NumPorts][]chan bool          // Ramp completion futures per port
+	pulseFutures  [NumPorts][]chan bool          // Pulse completion futures per port
+	subscribers   [NumPorts][]chan []any         // Broadcast sensor data subscribers per port
+	lineMatchers  []*lineMatcher                 // Temporary matchers for command replies
+	devices       [NumPorts]any                  // Device objects returned by Motor, ColorSensor...
+
+	// sensorTimeout bounds how long a sensor read waits for data
+	sensorTimeout time.Duration
+
+	// portTimeouts overrides sensorTimeout per port, zero meaning the default
+	portTimeouts [NumPorts]time.Duration
+
+	// voltagePollInterval is how often OnUnderVoltage samples the input voltage
+	voltagePollInterval time.Duration
+
+	// maxLineSize is the longest line the scanner accepts; longer lines are discarded
+	maxLineSize int
+
+	// describing is the port whose "list" description is being received, or -1
+	describing int
+
+	// Echo state: commands written while echo is on, awaiting their echoed line
+	echo          bool
+	pendingEchoes []string
+
+	// Protocol trace destination, set with SetTracer
+	traceMu sync.Mutex
+	tracer  io.Writer
+
+	// Last voltage and firmware version reported by the HAT
+	voltage         float64
+	firmwareVersion string
+
+	// Fatal read error, set once the reader gives up on the connection
+	readErr      error
+	onDisconnect func(error)
+
+	// Global sensor data hook, run from its own goroutine fed by sensorEvents
+	onSensorData func(SensorReading)
+	sensorEvents chan SensorReading
+
+	// Port connection hook, run from its own goroutine fed by portEvents
+	onPortChange func(PortEvent)
+	portEvents   chan PortEvent
+
+	// closed is set by Close; commands are refused afterwards. Writes hold writeMu for reading
+	// so Close can wait for in-flight commands before closing the port.
+	closed    bool
+	closeOnce sync.Once
+	writeMu   sync.RWMutex
 
 	// Firmware management
-	firmwareManager *FirmwareManager
+	firmwareManager    *FirmwareManager
+	skipFirmwareUpdate bool
+
+	// initialPowerLimit is sent as plimit by Initialize, zero meaning the HAT's default
+	initialPowerLimit float64
+
+	// optionErr records an invalid BrickOption value, returned by Initialize
+	optionErr error
+
+	metrics brickMetrics
 }
 
 // Connection represents a port connection
@@ -42,10 +138,74 @@ type Connection struct {
 	SimpleMode int
 	CombiMode  int
 	Data       []any
+
+	// reading is the structured form of Data, cleared once a read has consumed it
+	reading *SensorReading
+
+	// latest is the most recent reading, kept for snapshots until the device goes away
+	latest *SensorReading
+
+	// passive is set for devices reported as "connected to passive ID"
+	passive bool
+
+	// disconnected is set once a device that was connected goes away
+	disconnected bool
+
+	// combis holds the combi indices configured on the port, see ResetPort
+	combis map[int]bool
+
+	// format is the data format selected for formatMode, empty for plain or raw hex output
+	format     DataFormat
+	formatMode int
+
+	// modes describes the device's modes, as reported by "list"
+	modes []ModeDetails
+}
+
+// lineMatcher captures the first received line accepted by match, or every such line when keep is set
+type lineMatcher struct {
+	match  func(string) bool
+	future chan string
+	keep   bool
+}
+
+// BrickOption configures optional Brick settings
+type BrickOption func(*Brick)
+
+// WithMaxLineSize sets the longest line accepted from the HAT (default 64KB).
+// Longer input, e.g. binary garbage during a firmware transition, is discarded.
+func WithMaxLineSize(size int) BrickOption {
+	return func(b *Brick) {
+		if size > 0 {
+			b.maxLineSize = size
+		}
+	}
+}
+
+// WithoutFirmwareUpdate makes Initialize connect to whatever firmware is running, without
+// checking for the bootloader and flashing the embedded firmware, e.g. in CI or with custom
+// firmware. UpdateFirmware can still be called explicitly.
+func WithoutFirmwareUpdate() BrickOption {
+	return func(b *Brick) {
+		b.skipFirmwareUpdate = true
+	}
+}
+
+// WithInitialPowerLimit makes Initialize set the global power limit (0 to 1) of every port,
+// e.g. to keep a misconfigured motor from drawing full current on a first run. Without it
+// the HAT keeps its power-on default. A limit outside (0, 1] makes Initialize fail.
+func WithInitialPowerLimit(limit float64) BrickOption {
+	return func(b *Brick) {
+		if limit <= 0 || limit > 1 {
+			b.optionErr = fmt.Errorf("invalid initial power limit %g: must be greater than 0 and at most 1", limit)
+			return
+		}
+		b.initialPowerLimit = limit
+	}
 }
 
 // NewBrick creates a new BuildHat instance
-func NewBrick(reader io.Reader, writer io.Writer, logger *slog.Logger) *Brick {
+func NewBrick(reader io.Reader, writer io.Writer, logger *slog.Logger, opts ...BrickOption) *Brick {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -53,18 +213,24 @@ func NewBrick(reader io.Reader, writer io.Writer, logger *slog.Logger) *Brick {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	brick := &Brick{
-		input:          reader,
-		writer:         writer,
-		logger:         logger,
-		ctx:            ctx,
-		cancel:         cancel,
-		vinFutures:     make([]chan float64, 0),
-		versionFutures: make([]chan string, 0),
+		input:         reader,
+		writer:        writer,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+		sensorTimeout: 5 * time.Second,
+
+		voltagePollInterval: time.Second,
+		maxLineSize:         bufio.MaxScanTokenSize,
+		describing:          -1,
+	}
+	for _, opt := range opts {
+		opt(brick)
 	}
 
 	// Initialize sensor futures for each port
 	for i := range NumPorts {
-		brick.sensorFutures[i] = make([]chan []any, 0)
+		brick.sensorFutures[i] = make([]chan SensorReading, 0)
 		brick.rampFutures[i] = make([]chan bool, 0)
 		brick.pulseFutures[i] = make([]chan bool, 0)
 	}
@@ -81,24 +247,30 @@ func NewBrick(reader io.Reader, writer io.Writer, logger *slog.Logger) *Brick {
 	}
 
 	// Create scanner from input
-	brick.scanner = bufio.NewScanner(reader)
+	brick.scanner = brick.newScanner(reader)
 
 	// Start the reader thread
 	brick.wg.Add(1)
-	go brick.reader()
+	go brick.reader(ctx)
 
 	return brick
 }
 
 // Initialize initializes the BuildHat after creation
 func (b *Brick) Initialize() error {
+	if b.optionErr != nil {
+		return b.optionErr
+	}
+
 	b.logger.Info("Initializing BuildHat...")
 
 	// Wait a moment for the reader thread to start
 	time.Sleep(500 * time.Millisecond)
 
 	// Check and update firmware if needed
-	if err := b.firmwareManager.CheckAndUpdateFirmware(); err != nil {
+	if b.skipFirmwareUpdate {
+		b.logger.Info("Skipping firmware check")
+	} else if err := b.firmwareManager.CheckAndUpdateFirmware(); err != nil {
 		b.logger.Error("Firmware update failed", "error", err)
 		return fmt.Errorf("firmware update failed: %w", err)
 	}
@@ -111,6 +283,12 @@ func (b *Brick) Initialize() error {
 	// Wait a bit for initialization
 	time.Sleep(2 * time.Second)
 
+	if b.initialPowerLimit > 0 {
+		if err := b.writeCommand(PLimit(b.initialPowerLimit)); err != nil {
+			return err
+		}
+	}
+
 	// Send list command to scan devices
 	if err := b.writeCommand(List()); err != nil {
 		return err
@@ -123,55 +301,295 @@ func (b *Brick) Initialize() error {
 	return nil
 }
 
-// Close closes the BuildHat connection
+// Close closes the BuildHat connection. It is safe to call more than once and from several
+// goroutines; every call returns once shutdown has completed.
 func (b *Brick) Close() error {
-	// Close the serial port first to unblock the scanner
-	if closer, ok := b.writer.(io.Closer); ok {
-		closer.Close()
+	b.closeOnce.Do(func() {
+		b.writeMu.Lock()
+		b.mu.Lock()
+		b.closed = true
+		cancel, writer := b.cancel, b.writer
+		if b.sensorEvents != nil {
+			close(b.sensorEvents)
+		}
+		if b.portEvents != nil {
+			close(b.portEvents)
+		}
+		b.mu.Unlock()
+		b.writeMu.Unlock()
+
+		// Cancel the context first so the reader treats the closed port as a shutdown, not a disconnect
+		cancel()
+
+		// Close the serial port to unblock the scanner, then wait for goroutines to finish
+		if closer, ok := writer.(io.Closer); ok {
+			closer.Close()
+		}
+		b.wg.Wait()
+	})
+
+	return nil
+}
+
+// Reconnect swaps in new serial streams, e.g. after the USB serial device reappeared, and
+// re-runs device detection. Callbacks, subscriptions and device objects created from this
+// brick keep working. It must not be called concurrently with other brick operations.
+func (b *Brick) Reconnect(reader io.Reader, writer io.Writer) error {
+	if reader == nil || writer == nil {
+		return fmt.Errorf("reader and writer must not be nil")
+	}
+	if b.isClosed() {
+		return ErrBrickClosed
 	}
 
-	// Then cancel the context and wait for goroutines to finish
+	// Stop the old reader goroutine, closing the old port to unblock it
 	b.cancel()
+	if closer, ok := b.writer.(io.Closer); ok {
+		closer.Close()
+	}
 	b.wg.Wait()
 
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b.mu.Lock()
+	b.input = reader
+	b.writer = writer
+	b.scanner = b.newScanner(reader)
+	b.ctx = ctx
+	b.cancel = cancel
+	b.readErr = nil
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go b.reader(ctx)
+
+	b.logger.Info("Reconnected, scanning devices")
+	if err := b.writeCommand(List()); err != nil {
+		return fmt.Errorf("failed to scan devices: %w", err)
+	}
 	return nil
 }
 
-// reader is the main serial data reader thread
-func (b *Brick) reader() {
+// reader is the main serial data reader thread. It runs until ctx is cancelled.
+func (b *Brick) reader(ctx context.Context) {
 	defer b.wg.Done()
 
+	consecutiveErrors := 0
 	for {
 		select {
-		case <-b.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
 			if b.scanner.Scan() {
+				consecutiveErrors = 0
 				line := strings.TrimSpace(b.scanner.Text())
 				b.parseLine(line)
-			} else {
-				// Check for scanner error
-				if err := b.scanner.Err(); err != nil {
-					b.logger.Error("Scanner error", "error", err)
+				continue
+			}
+
+			// A bufio.Scanner stops for good once it fails, so start a fresh one:
+			// a transient read error then only loses the partial line.
+			err := b.scanner.Err()
+			if err != nil {
+				b.scanner = b.newScanner(b.input)
+			}
+
+			switch {
+			case err == nil:
+			case errors.Is(err, io.ErrNoProgress):
+				// Only idle reads returning no data, not a failure
+				b.logger.Debug("Scanner idle", "error", err)
+			case errors.Is(err, bufio.ErrTooLong):
+				// The data itself is bad, not the connection
+				b.logger.Warn("Discarding overlong line", "max_size", b.maxLineSize)
+			case ctx.Err() != nil:
+				return
+			default:
+				b.logger.Error("Scanner error", "error", err)
+				consecutiveErrors++
+				if consecutiveErrors >= maxConsecutiveReadErrors {
+					b.disconnect(err)
+					return
+				}
+			}
+
+			// Small delay to prevent busy waiting
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// newScanner creates a line scanner over input honouring the configured maximum line size
+func (b *Brick) newScanner(input io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, min(4096, b.maxLineSize)), b.maxLineSize)
+	scanner.Split(splitLines())
+	return scanner
+}
+
+// splitLines returns a bufio.SplitFunc ending lines at "\r\n", "\n" or a bare "\r", as the
+// HAT uses all three. A "\r" at the end of the buffered data is not held back waiting for a
+// possible "\n"; the split remembers it instead and drops that "\n" when it arrives.
+func splitLines() bufio.SplitFunc {
+	skipLF := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipLF && len(data) > 0 {
+			skipLF = false
+			if data[0] == '\n' {
+				return 1, nil, nil
+			}
+		}
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+			if data[i] == '\r' {
+				if i+1 == len(data) {
+					skipLF = true
+				} else if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
 				}
-				// Small delay to prevent busy waiting
-				time.Sleep(10 * time.Millisecond)
 			}
+			return i + 1, data[:i], nil
+		}
+
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// disconnect records a fatal read error and notifies the disconnect callback. It is called
+// from the reader goroutine, so the callback runs on its own goroutine: a callback calling
+// Reconnect must not wait for the reader it is running on.
+func (b *Brick) disconnect(err error) {
+	b.mu.Lock()
+	b.readErr = err
+	callback := b.onDisconnect
+	b.mu.Unlock()
+
+	b.logger.Error("Connection lost", "error", err)
+	if callback != nil {
+		go callback(err)
+	}
+}
+
+// Err returns the read error that stopped the brick from receiving, or nil while connected
+func (b *Brick) Err() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.readErr
+}
+
+// OnDisconnect registers a callback invoked once when reading from the HAT fails for good,
+// e.g. because the serial device went away. Recover with Reconnect once the device is back;
+// the callback runs on its own goroutine, so it may call Reconnect itself.
+func (b *Brick) OnDisconnect(fn func(error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onDisconnect = fn
+}
+
+// OnSensorData registers a callback receiving every sensor reading, from any port.
+// It runs on its own goroutine so a slow callback doesn't hold up the reader; readings
+// arriving while its queue is full are dropped. Pass nil to remove the callback.
+func (b *Brick) OnSensorData(fn func(SensorReading)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onSensorData = fn
+	if fn != nil && b.sensorEvents == nil && !b.closed {
+		b.sensorEvents = make(chan SensorReading, sensorHookBufferSize)
+		go b.dispatchSensorData(b.sensorEvents)
+	}
+}
+
+// dispatchSensorData hands queued readings to the OnSensorData callback until Close
+func (b *Brick) dispatchSensorData(events <-chan SensorReading) {
+	for reading := range events {
+		b.mu.RLock()
+		fn := b.onSensorData
+		b.mu.RUnlock()
+
+		if fn != nil {
+			fn(reading)
+		}
+	}
+}
+
+// PortEvent describes a device being plugged into or unplugged from a port
+type PortEvent struct {
+	Port      Port `json:"port"`
+	Connected bool `json:"connected"`
+	TypeID    int  `json:"type_id"` // Device type ID, -1 once unplugged
+	Passive   bool `json:"passive"` // True for passive devices such as lights and simple motors
+}
+
+// OnPortChange registers a callback invoked whenever the HAT reports a device connecting to
+// or disconnecting from a port, e.g. a sensor being unplugged. Like OnSensorData it runs on its
+// own goroutine, in the order the changes were reported. Pass nil to remove the callback.
+func (b *Brick) OnPortChange(fn func(PortEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onPortChange = fn
+	if fn != nil && b.portEvents == nil && !b.closed {
+		b.portEvents = make(chan PortEvent, portHookBufferSize)
+		go b.dispatchPortEvents(b.portEvents)
+	}
+}
+
+// dispatchPortEvents hands queued connection changes to the OnPortChange callback until Close
+func (b *Brick) dispatchPortEvents(events <-chan PortEvent) {
+	for event := range events {
+		b.mu.RLock()
+		fn := b.onPortChange
+		b.mu.RUnlock()
+
+		if fn != nil {
+			fn(event)
 		}
 	}
 }
 
+// emitPortEvent queues a connection change for the OnPortChange callback.
+// The caller must hold b.mu.
+func (b *Brick) emitPortEvent(event PortEvent) {
+	if b.onPortChange == nil || b.closed {
+		return
+	}
+	select {
+	case b.portEvents <- event:
+	default:
+		b.logger.Debug("Port change hook not keeping up, dropping event", "port", event.Port)
+	}
+}
+
 // parseLine parses incoming serial data
 func (b *Brick) parseLine(line string) {
 	// Log all received lines for debugging
 	if line != "" {
 		b.logger.Debug("RX", "line", line)
+		b.trace("RX<", line)
+		b.metrics.linesReceived.Add(1)
+	}
+
+	if b.isEcho(line) {
+		return
 	}
 
+	matched := b.notifyLineMatchers(line)
+
 	if b.tryParsePortMessage(line) {
 		return
 	}
 
+	if b.tryParseModeDescription(line) {
+		return
+	}
+
 	if b.tryParseVoltageReading(line) {
 		return
 	}
@@ -184,6 +602,13 @@ func (b *Brick) parseLine(line string) {
 		return
 	}
 
+	// A reply taken by a pending request, e.g. help output, is handled even though no parser knows it
+	if matched {
+		return
+	}
+	if line != "" {
+		b.metrics.parseFailures.Add(1)
+	}
 	b.logger.Debug("Unhandled line", "line", line)
 }
 
@@ -208,40 +633,47 @@ func (b *Brick) tryParsePortMessage(line string) bool {
 // tryParseVoltageReading attempts to parse voltage readings.
 // Example: "7.85 V"
 func (b *Brick) tryParseVoltageReading(line string) bool {
-	if len(line) < 3 || !strings.HasSuffix(line, " V") {
-		return false
+	voltage, ok := parseVoltageLine(line)
+	if ok {
+		b.handleVoltageReading(voltage)
 	}
+	return ok
+}
 
-	parts := strings.Split(line, " ")
-	if len(parts) < 1 {
-		return false
+// parseVoltageLine parses a voltage reading such as "7.85 V"
+func parseVoltageLine(line string) (float64, bool) {
+	if len(line) < 3 || !strings.HasSuffix(line, " V") {
+		return 0, false
 	}
 
+	parts := strings.Split(line, " ")
 	voltage, err := strconv.ParseFloat(parts[0], 64)
 	if err != nil {
-		return false
+		return 0, false
 	}
-
-	b.handleVoltageReading(voltage)
-	return true
+	return voltage, true
 }
 
 // tryParseVersionResponse attempts to parse version responses.
 // Examples: "Firmware version: 20201016.10", "BuildHAT bootloader version 0.0.1"
 func (b *Brick) tryParseVersionResponse(line string) bool {
-	// Handle firmware version responses
-	if version, ok := strings.CutPrefix(line, "Firmware version: "); ok {
+	version, ok := parseVersionLine(line)
+	if ok {
 		b.handleVersionResponse(version)
-		return true
 	}
+	return ok
+}
 
-	// Handle bootloader version responses
+// parseVersionLine parses a version response. Firmware versions are returned without their
+// "Firmware version: " prefix, the bootloader banner as a whole.
+func parseVersionLine(line string) (string, bool) {
+	if version, ok := strings.CutPrefix(line, "Firmware version: "); ok {
+		return version, true
+	}
 	if strings.HasPrefix(line, "BuildHAT bootloader version") {
-		b.handleVersionResponse(line)
-		return true
+		return line, true
 	}
-
-	return false
+	return "", false
 }
 
 // tryParseSensorData attempts to parse sensor data.
@@ -273,6 +705,66 @@ func (b *Brick) tryParseSensorData(line string) bool {
 	return true
 }
 
+// notifyLineMatchers delivers the line to every registered matcher that accepts it, reporting
+// whether any did. Matched entries are removed so each matcher fires at most once, except collectors.
+func (b *Brick) notifyLineMatchers(line string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	matched := false
+	remaining := b.lineMatchers[:0]
+	for _, m := range b.lineMatchers {
+		if !m.match(line) {
+			remaining = append(remaining, m)
+			continue
+		}
+		matched = true
+		if !m.keep {
+			m.future <- line
+			continue
+		}
+		select {
+		case m.future <- line:
+		default:
+			b.logger.Warn("Line collector full, dropping line", "line", line)
+		}
+		remaining = append(remaining, m)
+	}
+	b.lineMatchers = remaining
+	return matched
+}
+
+// addLineMatcher registers a matcher for the next received line accepted by match
+func (b *Brick) addLineMatcher(match func(string) bool) *lineMatcher {
+	matcher := &lineMatcher{match: match, future: make(chan string, 1)}
+	b.mu.Lock()
+	b.lineMatchers = append(b.lineMatchers, matcher)
+	b.mu.Unlock()
+	return matcher
+}
+
+// addLineCollector registers a matcher that receives every line accepted by match until removed
+func (b *Brick) addLineCollector(match func(string) bool) *lineMatcher {
+	collector := &lineMatcher{match: match, future: make(chan string, lineCollectorBufferSize), keep: true}
+	b.mu.Lock()
+	b.lineMatchers = append(b.lineMatchers, collector)
+	b.mu.Unlock()
+	return collector
+}
+
+// removeLineMatcher unregisters a matcher if it is still pending
+func (b *Brick) removeLineMatcher(matcher *lineMatcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, m := range b.lineMatchers {
+		if m == matcher {
+			b.lineMatchers = append(b.lineMatchers[:i], b.lineMatchers[i+1:]...)
+			return
+		}
+	}
+}
+
 // handlePortMessage handles port connection/disconnection messages.
 // msg includes the colon prefix, e.g., ": ramp done", ": connected to active ID 3D"
 func (b *Brick) handlePortMessage(portID int, msg string) {
@@ -320,8 +812,12 @@ func (b *Brick) handlePortMessage(portID int, msg string) {
 		if len(parts) >= 6 {
 			hexStr := parts[5] // The type ID is the 6th part (index 5)
 			if typeID, err := strconv.ParseInt(hexStr, 16, 32); err == nil {
+				b.countReconnect(portID)
 				b.connections[portID].TypeID = int(typeID)
 				b.connections[portID].Connected = true
+				b.connections[portID].passive = false
+				b.emitPortEvent(PortEvent{Port: Port(portID), Connected: true, TypeID: int(typeID)})
+				b.beginModeDescription(portID)
 			} else {
 				b.logger.Error("Failed to parse type ID", "port", portID, "hex", hexStr, "error", err)
 			}
@@ -332,89 +828,169 @@ func (b *Brick) handlePortMessage(portID int, msg string) {
 		if len(parts) >= 6 {
 			hexStr := parts[5] // The type ID is the 6th part (index 5)
 			if typeID, err := strconv.ParseInt(hexStr, 16, 32); err == nil {
+				b.countReconnect(portID)
 				b.connections[portID].TypeID = int(typeID)
 				b.connections[portID].Connected = true
+				b.connections[portID].passive = true
+				b.emitPortEvent(PortEvent{Port: Port(portID), Connected: true, TypeID: int(typeID), Passive: true})
+				b.beginModeDescription(portID)
 			} else {
 				b.logger.Error("Failed to parse passive type ID", "port", portID, "hex", hexStr, "error", err)
 			}
 		}
 	case strings.Contains(msg, "disconnected"):
+		if b.connections[portID].Connected {
+			b.connections[portID].disconnected = true
+			b.emitPortEvent(PortEvent{Port: Port(portID), TypeID: -1})
+		}
 		b.connections[portID].TypeID = -1
 		b.connections[portID].Connected = false
+		b.connections[portID].latest = nil
+		b.connections[portID].combis = nil
+		b.connections[portID].format = ""
+		b.devices[portID] = nil
+		b.endModeDescription(portID)
 	case strings.Contains(msg, "no device detected"):
+		if b.connections[portID].Connected {
+			b.connections[portID].disconnected = true
+			b.emitPortEvent(PortEvent{Port: Port(portID), TypeID: -1})
+		}
 		b.connections[portID].TypeID = -1
 		b.connections[portID].Connected = false
+		b.connections[portID].latest = nil
+		b.connections[portID].combis = nil
+		b.connections[portID].format = ""
+		b.devices[portID] = nil
+		b.endModeDescription(portID)
 	}
 }
 
 // handleVoltageReading handles voltage readings
 func (b *Brick) handleVoltageReading(voltage float64) {
-	if len(b.vinFutures) > 0 {
-		future := b.vinFutures[0]
-		b.vinFutures = b.vinFutures[1:]
-		select {
-		case future <- voltage:
-		default:
-		}
-	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.voltage = voltage
 }
 
 // handleVersionResponse handles version responses
 func (b *Brick) handleVersionResponse(version string) {
-	if len(b.versionFutures) > 0 {
-		future := b.versionFutures[0]
-		b.versionFutures = b.versionFutures[1:]
-		select {
-		case future <- version:
-		default:
-		}
-	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.firmwareVersion = version
+}
+
+// sensorDataReceiver is implemented by device objects that keep live values up to date
+// from every reading on their port, see cachedDevice
+type sensorDataReceiver interface {
+	updateFromSensorData(reading SensorReading)
 }
 
 // handleSensorData handles sensor data.
 // line is the full message, e.g., "P0M1: 123 456" (port 0, mode 1, values 123 and 456)
 func (b *Brick) handleSensorData(port Port, line string) {
+	reading, device, ok := b.storeSensorData(port, line)
+	if !ok {
+		return
+	}
+
+	// Outside b.mu: device objects take their own lock, and hold it while calling the brick
+	if receiver, ok := device.(sensorDataReceiver); ok {
+		receiver.updateFromSensorData(reading)
+	}
+}
+
+// storeSensorData parses a sensor data line and hands the reading to waiting reads,
+// subscribers and the OnSensorData hook. It returns the reading and the device object
+// cached for the port, if any; ok is false for a line too short to be a reading.
+func (b *Brick) storeSensorData(port Port, line string) (reading SensorReading, device any, ok bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	// Ensure line is long enough to slice
 	if len(line) < 5 {
 		b.logger.Debug("Sensor data too short", "port", port, "line", line)
-		return
+		return SensorReading{}, nil, false
 	}
 
-	// Parse sensor data (simplified)
-	parts := strings.Split(line[5:], " ")
-	data := make([]any, 0, len(parts))
-
-	for _, part := range parts {
-		if part == "" {
-			continue
-		}
-		if strings.Contains(part, ".") {
-			if val, err := strconv.ParseFloat(part, 64); err == nil {
-				data = append(data, val)
-			}
-		} else {
-			if val, err := strconv.ParseInt(part, 10, 32); err == nil {
-				data = append(data, int(val))
-			}
-		}
+	portID := port.Int()
+	reading = parseSensorReading(port, line)
+	if conn := b.connections[portID]; conn.format != "" && !reading.Combi && reading.Mode == conn.formatMode {
+		reading.applyFormat(conn.format)
 	}
+	data := reading.data
 
-	portID := port.Int()
 	b.connections[portID].Data = data
-	b.logger.Debug("Sensor data", "port", portID, "data", data)
+	b.connections[portID].reading = &reading
+	b.connections[portID].latest = &reading
+	b.logger.Debug("Sensor data", "port", portID, "mode", reading.Mode, "data", data)
 
-	// Notify any waiting sensor futures
+	// Notify any waiting sensor futures. A delivered reading is consumed, so it must not
+	// also be served from the cache to the next read.
 	if len(b.sensorFutures[portID]) > 0 {
 		future := b.sensorFutures[portID][0]
 		b.sensorFutures[portID] = b.sensorFutures[portID][1:]
 		select {
-		case future <- data:
+		case future <- reading:
+			b.connections[portID].Data = nil
+			b.connections[portID].reading = nil
+		default:
+		}
+	}
+
+	// Fan out to all subscribers, dropping the reading for any that are not keeping up. Each
+	// gets its own copy so one modifying it cannot affect the others or the cached data.
+	for _, sub := range b.subscribers[portID] {
+		select {
+		case sub <- slices.Clone(data):
+		default:
+		}
+	}
+
+	if b.onSensorData != nil && !b.closed {
+		select {
+		case b.sensorEvents <- reading:
 		default:
+			b.logger.Debug("Sensor data hook not keeping up, dropping reading", "port", portID)
 		}
 	}
+
+	return reading, b.devices[portID], true
+}
+
+// Subscribe returns a channel that receives every reading parsed for the port,
+// along with a function that cancels the subscription and closes the channel.
+// Unlike one-shot reads, every subscriber sees every reading. Readings are dropped
+// for a subscriber whose buffer is full.
+func (b *Brick) Subscribe(port Port) (<-chan []any, func()) {
+	sub := make(chan []any, subscriberBufferSize)
+	if !port.IsValid() {
+		close(sub)
+		return sub, func() {}
+	}
+
+	portID := port.Int()
+	b.mu.Lock()
+	b.subscribers[portID] = append(b.subscribers[portID], sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			subs := b.subscribers[portID]
+			for i, s := range subs {
+				if s == sub {
+					b.subscribers[portID] = append(subs[:i], subs[i+1:]...)
+					break
+				}
+			}
+			close(sub)
+		})
+	}
+
+	return sub, unsubscribe
 }
 
 // writeCommand sends a command to the BuildHat
@@ -424,42 +1000,327 @@ func (b *Brick) writeCommand(command Command) error {
 		cmd += "\r"
 	}
 
+	b.writeMu.RLock()
+	defer b.writeMu.RUnlock()
+
+	b.mu.RLock()
+	closed, writer := b.closed, b.writer
+	b.mu.RUnlock()
+	if closed {
+		return ErrBrickClosed
+	}
+
 	b.logger.Debug("TX", "cmd", strings.TrimSuffix(cmd, "\r"))
-	_, err := b.writer.Write([]byte(cmd))
+	b.trace("TX>", strings.TrimSuffix(cmd, "\r"))
+	b.expectEcho(strings.TrimSpace(cmd))
+	b.trackSelectedFormats(command)
+	_, err := writer.Write([]byte(cmd))
+	if err == nil {
+		b.metrics.commandsSent.Add(1)
+	}
 	return err
 }
 
-// GetHardwareVersion gets the hardware version
-func (b *Brick) GetHardwareVersion() (string, error) {
-	future := make(chan string, 1)
-	b.versionFutures = append(b.versionFutures, future)
+// trackSelectedFormats records the data format of every select or selonce in command, so
+// readings of that mode are decoded with the right width and signedness. It is called before
+// the command is written, as the reply can arrive before the write returns.
+func (b *Brick) trackSelectedFormats(command Command) {
+	commands := []Command{command}
+	if compound, ok := command.(*CompoundCommand); ok {
+		commands = compound.commands
+	}
 
-	if err := b.writeCommand(Version()); err != nil {
-		return "", err
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var conn *Connection
+	for _, cmd := range commands {
+		var mode *int
+		var format *DataFormat
+		switch c := cmd.(type) {
+		case *PortCommand:
+			if c.port.IsValid() {
+				conn = b.connections[c.port]
+			}
+			continue
+		case *SelectCommand:
+			mode, format = c.mode, c.format
+		case *SelectOnceCommand:
+			mode, format = c.mode, c.format
+		default:
+			continue
+		}
+
+		if conn == nil {
+			continue
+		}
+		conn.format = ""
+		if mode != nil && format != nil {
+			conn.format = *format
+			conn.formatMode = *mode
+		}
+	}
+}
+
+// SetTracer writes a timestamped line for every command sent ("TX>") and line received ("RX<")
+// to w, regardless of the log level. Pass nil to stop tracing.
+func (b *Brick) SetTracer(w io.Writer) {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	b.tracer = w
+}
+
+// trace writes one protocol line to the tracer, if any
+func (b *Brick) trace(direction, line string) {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+
+	if b.tracer == nil {
+		return
+	}
+	fmt.Fprintf(b.tracer, "%s %s %s\n", time.Now().Format("15:04:05.000000"), direction, line)
+}
+
+// isClosed reports whether Close has been called
+func (b *Brick) isClosed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.closed
+}
+
+// SetEcho enables or disables the HAT echoing back every command it receives.
+// Echoed commands are recognised and skipped rather than parsed as replies.
+func (b *Brick) SetEcho(enable bool) error {
+	if !enable {
+		b.mu.Lock()
+		b.echo = false
+		b.pendingEchoes = nil
+		b.mu.Unlock()
+	}
+
+	if err := b.writeCommand(Echo(enable)); err != nil {
+		return err
+	}
+
+	if enable {
+		b.mu.Lock()
+		b.echo = true
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// expectEcho remembers a written command so its echo can be skipped
+func (b *Brick) expectEcho(cmd string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.echo {
+		return
+	}
+	b.pendingEchoes = append(b.pendingEchoes, cmd)
+	if len(b.pendingEchoes) > maxPendingEchoes {
+		b.pendingEchoes = b.pendingEchoes[1:]
+	}
+}
+
+// isEcho reports whether a line is the echo of a written command, consuming it.
+// Earlier commands whose echo never arrived are dropped too.
+func (b *Brick) isEcho(line string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, cmd := range b.pendingEchoes {
+		if cmd == line {
+			b.pendingEchoes = b.pendingEchoes[i+1:]
+			return true
+		}
+	}
+	return false
+}
+
+// countReconnect counts a device appearing on a port that lost its previous device.
+// The caller must hold b.mu.
+func (b *Brick) countReconnect(portID int) {
+	conn := b.connections[portID]
+	if conn.disconnected {
+		b.metrics.portReconnects[portID].Add(1)
+		conn.disconnected = false
+	}
+}
+
+// SendRawCommand sends an arbitrary protocol command to the BuildHat without waiting for a reply
+func (b *Brick) SendRawCommand(command string) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return fmt.Errorf("raw command must not be empty")
+	}
+	return b.writeCommand(Raw(command))
+}
+
+// SendRawCommandAwait sends an arbitrary protocol command and returns the first
+// received line accepted by match. The matcher is removed once it fires, when ctx
+// is done, or when the brick is closed. match runs on the reader goroutine and must not block.
+func (b *Brick) SendRawCommandAwait(ctx context.Context, command string, match func(string) bool) (string, error) {
+	if match == nil {
+		return "", fmt.Errorf("match function must not be nil")
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", fmt.Errorf("raw command must not be empty")
+	}
+
+	return request(ctx, b, Raw(command), func(line string) (string, bool) {
+		return line, match(line)
+	})
+}
+
+// request sends cmd and returns the first received line parse accepts, decoded by parse.
+// The reply is awaited from just before the command is written, and the wait is always
+// unregistered on return, whether the reply arrived, ctx is done or the brick is closed.
+// parse runs on the reader goroutine and must not block.
+func request[T any](ctx context.Context, b *Brick, cmd Command, parse func(line string) (T, bool)) (T, error) {
+	var zero T
+	matcher := b.addLineMatcher(func(line string) bool {
+		_, ok := parse(line)
+		return ok
+	})
+	defer b.removeLineMatcher(matcher)
+
+	if err := b.writeCommand(cmd); err != nil {
+		return zero, err
 	}
 
 	select {
-	case version := <-future:
-		return version, nil
-	case <-time.After(5 * time.Second):
+	case line := <-matcher.future:
+		value, _ := parse(line)
+		return value, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-b.ctx.Done():
+		return zero, fmt.Errorf("waiting for reply: %w", ErrBrickClosed)
+	}
+}
+
+// GetCommandHelp sends "help" and returns the command synopsis lines printed by the HAT, e.g.
+// "vin : print the input voltage". The reply ends at the prompt or once the HAT goes quiet.
+func (b *Brick) GetCommandHelp() ([]string, error) {
+	collector := b.addLineCollector(func(line string) bool {
+		return line != "" && !isPortLine(line)
+	})
+	defer b.removeLineMatcher(collector)
+
+	if err := b.writeCommand(Help()); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	timeout := time.After(5 * time.Second)
+	for {
+		var quiet <-chan time.Time
+		if len(lines) > 0 {
+			quiet = time.After(helpQuietPeriod)
+		}
+
+		select {
+		case line := <-collector.future:
+			switch {
+			case line == Help().CommandString():
+				// Echo of the command
+			case strings.HasSuffix(line, ">"):
+				return lines, nil
+			default:
+				lines = append(lines, line)
+			}
+		case <-quiet:
+			return lines, nil
+		case <-timeout:
+			if len(lines) == 0 {
+				b.metrics.timeouts.Add(1)
+				return nil, fmt.Errorf("timeout waiting for help response")
+			}
+			return lines, nil
+		case <-b.ctx.Done():
+			return nil, fmt.Errorf("brick closed while waiting for help response")
+		}
+	}
+}
+
+// isPortLine reports whether a line is port traffic ("P0: ...", "P0M1: ...", "P0C0: ...")
+// rather than a reply to a global command
+func isPortLine(line string) bool {
+	return len(line) >= 3 && line[0] == 'P' && line[1] >= '0' && line[1] <= '9' &&
+		(line[2] == ':' || line[2] == 'M' || line[2] == 'C')
+}
+
+// GetHardwareVersion gets the hardware version
+func (b *Brick) GetHardwareVersion() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	version, err := request(ctx, b, Version(), parseVersionLine)
+	if errors.Is(err, context.DeadlineExceeded) {
+		b.metrics.timeouts.Add(1)
 		return "", fmt.Errorf("timeout waiting for version response")
 	}
+	return version, err
 }
 
 // GetVoltage gets the input voltage
 func (b *Brick) GetVoltage() (float64, error) {
-	future := make(chan float64, 1)
-	b.vinFutures = append(b.vinFutures, future)
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
 
-	if err := b.writeCommand(Vin()); err != nil {
-		return 0, err
+	voltage, err := request(ctx, b, Vin(), parseVoltageLine)
+	if errors.Is(err, context.DeadlineExceeded) {
+		b.metrics.timeouts.Add(1)
+		return 0, fmt.Errorf("timeout waiting for voltage response")
 	}
+	return voltage, err
+}
 
-	select {
-	case voltage := <-future:
-		return voltage, nil
-	case <-time.After(5 * time.Second):
-		return 0, fmt.Errorf("timeout waiting for voltage response")
+// OnUnderVoltage polls the input voltage in the background and calls fn once when it drops
+// below threshold, and once more when it recovers above threshold plus a small hysteresis.
+// fn is called from the polling goroutine. Polling stops when the returned function is
+// called or the brick is closed.
+func (b *Brick) OnUnderVoltage(threshold float64, fn func(v float64)) func() {
+	stop := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(b.voltagePollInterval)
+		defer ticker.Stop()
+
+		below := false
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			voltage, err := b.GetVoltage()
+			if errors.Is(err, ErrBrickClosed) {
+				return
+			}
+			if err != nil {
+				b.logger.Warn("Voltage poll failed", "error", err)
+				continue
+			}
+
+			switch {
+			case !below && voltage < threshold:
+				below = true
+				fn(voltage)
+			case below && voltage >= threshold+underVoltageHysteresis:
+				below = false
+				fn(voltage)
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stop) })
 	}
 }
 
@@ -468,62 +1329,475 @@ func (b *Brick) ScanDevices() error {
 	return b.writeCommand(List())
 }
 
+// WriteBytesToSensor writes raw bytes to the device on the given port.
+// singleHeader selects a write1 (1-byte header) message, otherwise write2 (2-byte header) is used.
+func (b *Brick) WriteBytesToSensor(port Port, data []byte, singleHeader bool) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+
+	var write Command
+	if singleHeader {
+		write = Write1(data...)
+	} else {
+		write = Write2(data...)
+	}
+
+	return b.writeCommand(Compound(SelectPort(port), write))
+}
+
+// SetPortPowerLimit caps the output power of a port (0 to 1) whatever device is attached,
+// e.g. to limit the current drawn by a light or third-party actuator
+func (b *Brick) SetPortPowerLimit(port Port, limit float64) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	if limit < 0 || limit > 1 {
+		return fmt.Errorf("power limit must be between 0 and 1")
+	}
+	return b.writeCommand(Compound(SelectPort(port), PortPLimit(limit)))
+}
+
+// SetMotorBias sets the drive bias of a port (0 to 1), the offset the HAT adds to
+// non-zero outputs to overcome the motor's static friction
+func (b *Brick) SetMotorBias(port Port, bias float64) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	if bias < 0 || bias > 1 {
+		return fmt.Errorf("bias must be between 0 and 1")
+	}
+	return b.writeCommand(Compound(SelectPort(port), Bias(bias)))
+}
+
+// SetBias is a shorthand for SetMotorBias
+func (b *Brick) SetBias(port Port, bias float64) error {
+	return b.SetMotorBias(port, bias)
+}
+
+// PortOff switches a port's output off ("off", i.e. pwm ; set 0)
+func (b *Brick) PortOff(port Port) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	return b.writeCommand(Compound(SelectPort(port), Off()))
+}
+
+// PortOn drives a port's output at full power ("on", i.e. pwm ; set 1)
+func (b *Brick) PortOn(port Port) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	return b.writeCommand(Compound(SelectPort(port), On()))
+}
+
+// combiSelectRate is the interval in milliseconds between readings streamed from a selected combi mode
+const combiSelectRate = 10
+
+// ConfigureCombi sets up combi mode index on a port to report several mode datasets in one
+// reading, e.g. speed, position and absolute position for a motor
+func (b *Brick) ConfigureCombi(port Port, index int, datasets ...ModeDataset) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	if index < 0 {
+		return fmt.Errorf("invalid combi index: %d", index)
+	}
+	if len(datasets) == 0 {
+		return fmt.Errorf("combi mode needs at least one dataset")
+	}
+
+	if err := b.writeCommand(Compound(SelectPort(port), Combi(index, datasets...))); err != nil {
+		return err
+	}
+	b.markCombi(port, index)
+	return nil
+}
+
+// markCombi records that a combi index is configured on a port
+func (b *Brick) markCombi(port Port, index int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	conn := b.connections[port]
+	if conn.combis == nil {
+		conn.combis = make(map[int]bool)
+	}
+	conn.combis[index] = true
+}
+
+// ResetPort returns a port to a neutral state: it deselects any mode, deconfigures the combi
+// indices configured through the brick (always including 0) and coasts any motor. Use it before
+// using a port differently, e.g. reading other sensor modes after streaming a combi.
+func (b *Brick) ResetPort(port Port) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+
+	b.mu.RLock()
+	indices := []int{0}
+	for index := range b.connections[port].combis {
+		if index != 0 {
+			indices = append(indices, index)
+		}
+	}
+	b.mu.RUnlock()
+	slices.Sort(indices)
+
+	cmds := []Command{SelectPort(port), SelectDeselect()}
+	for _, index := range indices {
+		cmds = append(cmds, CombiDeconfigure(index))
+	}
+	cmds = append(cmds, Coast())
+	if err := b.writeCommand(Compound(cmds...)); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.connections[port].combis = nil
+	b.mu.Unlock()
+	return nil
+}
+
+// SelectCombi starts streaming readings from a combi mode configured with ConfigureCombi
+func (b *Brick) SelectCombi(port Port, index int) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	if index < 0 {
+		return fmt.Errorf("invalid combi index: %d", index)
+	}
+
+	return b.writeCommand(Compound(SelectPort(port), Select(index), SelRate(combiSelectRate)))
+}
+
+// selectModeAndRead selects a mode on a port and waits for its next reading. With readOnce
+// the mode is selected with "selonce", so the HAT sends a single reading and stops instead of
+// streaming until the next select.
+func (b *Brick) selectModeAndRead(port Port, mode int, readOnce bool) ([]any, error) {
+	sel := Select(mode)
+	if readOnce {
+		sel = SelectOnce(mode)
+	}
+	if err := b.writeCommand(Compound(SelectPort(port), sel)); err != nil {
+		return nil, err
+	}
+	return b.getSensorData(port)
+}
+
+// ReadModeValue reads a single value of a mode: the value at offset, in format, from the next
+// reading. The mode is selected once with that offset and format, so the HAT sends the decoded
+// value; raw hex output is decoded here according to format.
+func (b *Brick) ReadModeValue(port Port, mode, offset int, format DataFormat) (float64, error) {
+	if !port.IsValid() {
+		return 0, fmt.Errorf("invalid port: %d", port)
+	}
+	if mode < 0 || offset < 0 {
+		return 0, fmt.Errorf("invalid mode %d or offset %d", mode, offset)
+	}
+	if _, err := decodeDataFormat(make([]byte, 4), format); err != nil {
+		return 0, err
+	}
+
+	if err := b.writeCommand(Compound(SelectPort(port), SelectOnceFormatted(mode, offset, format))); err != nil {
+		return 0, err
+	}
+	reading, err := b.getSensorReading(port)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(reading.Values) > 0 {
+		return reading.Values[0], nil
+	}
+	if len(reading.RawBytes) > 0 {
+		return decodeDataFormat(reading.RawBytes, format)
+	}
+	return 0, fmt.Errorf("no data received for mode %d on port %s", mode, port)
+}
+
+// SelectCombiModesAndRead configures combi index 0 on a port to report the first value of
+// each of modes, selects it and waits for the next reading, e.g. color, reflected and ambient
+// light together from a color sensor. With readOnce a single reading is requested with
+// "selonce"; otherwise readings keep streaming until another mode is selected.
+func (b *Brick) SelectCombiModesAndRead(port Port, modes []int, readOnce bool) ([]any, error) {
+	if !port.IsValid() {
+		return nil, fmt.Errorf("invalid port: %d", port)
+	}
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("combi mode needs at least one dataset")
+	}
+
+	datasets := make([]ModeDataset, len(modes))
+	for i, mode := range modes {
+		if mode < 0 {
+			return nil, fmt.Errorf("invalid mode: %d", mode)
+		}
+		datasets[i] = NewModeDataset(mode, 0)
+	}
+
+	sel := Select(0)
+	if readOnce {
+		sel = SelectOnce(0)
+	}
+	if err := b.writeCommand(Compound(SelectPort(port), Combi(0, datasets...), sel)); err != nil {
+		return nil, err
+	}
+	b.markCombi(port, 0)
+	return b.getSensorData(port)
+}
+
 // getSensorData waits for sensor data from a specific port
 func (b *Brick) getSensorData(port Port) ([]any, error) {
+	reading, err := b.getSensorReading(port)
+	if err != nil {
+		return nil, err
+	}
+	return reading.data, nil
+}
+
+// GetSensorReading waits for the next reading from a port, including its mode and timestamp
+func (b *Brick) GetSensorReading(port Port) (SensorReading, error) {
+	if !port.IsValid() {
+		return SensorReading{}, fmt.Errorf("invalid port: %d", port)
+	}
+	return b.getSensorReading(port)
+}
+
+// getSensorReading waits for a structured reading from a specific port
+func (b *Brick) getSensorReading(port Port) (SensorReading, error) {
 	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return SensorReading{}, ErrBrickClosed
+	}
 	portID := port.Int()
 
-	// Check if we already have cached data
-	if len(b.connections[portID].Data) > 0 {
-		data := b.connections[portID].Data
+	// Check if we already have a cached reading
+	if cached := b.connections[portID].reading; cached != nil {
 		// Clear cached data so next call gets fresh data
 		b.connections[portID].Data = nil
+		b.connections[portID].reading = nil
 		b.mu.Unlock()
-		return data, nil
+		return *cached, nil
 	}
 
 	// No cached data, create a future and wait for new data
-	future := make(chan []any, 1)
+	future := make(chan SensorReading, 1)
 	b.sensorFutures[portID] = append(b.sensorFutures[portID], future)
 	b.mu.Unlock()
 
+	timeout := b.sensorTimeoutFor(port)
 	select {
-	case data := <-future:
-		return data, nil
-	case <-time.After(5 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for sensor data on port %d", port)
+	case reading := <-future:
+		return reading, nil
+	case <-time.After(timeout):
+		b.removeSensorFuture(portID, future)
+		// A reading delivered just before the future was removed is no longer cached anywhere else
+		select {
+		case reading := <-future:
+			return reading, nil
+		default:
+		}
+		b.metrics.timeouts.Add(1)
+		return SensorReading{}, fmt.Errorf("%w on port %d after %v", ErrSensorTimeout, port, timeout)
 	}
 }
 
-// GetDeviceInfo returns information about devices on all ports
-func (b *Brick) GetDeviceInfo() map[Port]DeviceInfo {
+// SetSensorTimeout sets how long reads from a port wait for data before failing with
+// ErrSensorTimeout, e.g. to fail fast on a slow polling loop. Zero restores the default.
+func (b *Brick) SetSensorTimeout(port Port, timeout time.Duration) error {
+	if !port.IsValid() {
+		return fmt.Errorf("invalid port: %d", port)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("sensor timeout must not be negative")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.portTimeouts[port] = timeout
+	return nil
+}
+
+// sensorTimeoutFor returns how long reads from a port wait for data
+func (b *Brick) sensorTimeoutFor(port Port) time.Duration {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
+	if timeout := b.portTimeouts[port]; timeout > 0 {
+		return timeout
+	}
+	return b.sensorTimeout
+}
 
-	devices := make(map[Port]DeviceInfo)
+// removeSensorFuture unregisters a pending sensor future so it cannot swallow later readings
+func (b *Brick) removeSensorFuture(portID int, future chan SensorReading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	futures := b.sensorFutures[portID]
+	for i, f := range futures {
+		if f == future {
+			b.sensorFutures[portID] = append(futures[:i], futures[i+1:]...)
+			return
+		}
+	}
+}
+
+// StopAll coasts every port with a motor connected in a single command, then releases all
+// movements waiting for their ramp or pulse to complete, which return ErrStopped.
+func (b *Brick) StopAll() error {
+	b.mu.Lock()
+	var commands []Command
+	for _, port := range AllPorts() {
+		switch getDeviceCategory(b.connections[port].TypeID) {
+		case DeviceCategoryMotor, DeviceCategoryPassiveMotor:
+			commands = append(commands, SelectPort(port), Coast())
+		}
+	}
+	b.mu.Unlock()
+
+	var err error
+	if len(commands) > 0 {
+		err = b.writeCommand(Compound(commands...))
+	}
+
+	// A completion future closed without a value reports an interrupted movement
+	b.mu.Lock()
 	for i := range NumPorts {
-		port := Port(i)
-		conn := b.connections[i]
+		for _, future := range b.rampFutures[i] {
+			close(future)
+		}
+		for _, future := range b.pulseFutures[i] {
+			close(future)
+		}
+		b.rampFutures[i] = b.rampFutures[i][:0]
+		b.pulseFutures[i] = b.pulseFutures[i][:0]
+	}
+	b.mu.Unlock()
 
-		devices[port] = DeviceInfo{
-			Port:      port,
-			TypeID:    conn.TypeID,
-			Connected: conn.Connected,
-			Name:      getDeviceName(conn.TypeID),
-			Category:  getDeviceCategory(conn.TypeID),
+	return err
+}
+
+// removeRampFuture unregisters a pending ramp future so it cannot take a later "ramp done"
+func (b *Brick) removeRampFuture(portID int, future chan bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	futures := b.rampFutures[portID]
+	for i, f := range futures {
+		if f == future {
+			b.rampFutures[portID] = append(futures[:i], futures[i+1:]...)
+			return
 		}
 	}
+}
+
+// removePulseFuture unregisters a pending pulse future so it cannot take a later "pulse done"
+func (b *Brick) removePulseFuture(portID int, future chan bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	futures := b.pulseFutures[portID]
+	for i, f := range futures {
+		if f == future {
+			b.pulseFutures[portID] = append(futures[:i], futures[i+1:]...)
+			return
+		}
+	}
+}
+
+// LatestSensorData returns a copy of the cached reading for a port without consuming it.
+// Sensor reads consume the cache so that the next read waits for fresh data; peeking
+// leaves the cache in place, so a pending or subsequent read still receives the same reading.
+// The boolean is false when no unconsumed reading is cached.
+func (b *Brick) LatestSensorData(port Port) ([]any, bool) {
+	if !port.IsValid() {
+		return nil, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	data := b.connections[port.Int()].Data
+	if len(data) == 0 {
+		return nil, false
+	}
+	return append([]any(nil), data...), true
+}
+
+// Ports returns the brick's ports in order, A to D
+func (b *Brick) Ports() []Port {
+	return AllPorts()
+}
+
+// GetDeviceInfo returns information about devices on all ports
+func (b *Brick) GetDeviceInfo() map[Port]DeviceInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	devices := make(map[Port]DeviceInfo)
+	for _, port := range AllPorts() {
+		devices[port] = b.deviceInfo(port)
+	}
 
 	return devices
 }
 
+// DeviceType returns the type ID of the device on a port, and whether a device is connected
+func (b *Brick) DeviceType(port Port) (int, bool) {
+	if !port.IsValid() {
+		return -1, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	conn := b.connections[port]
+	return conn.TypeID, conn.Connected
+}
+
+// IsMotor reports whether an active motor, usable with Motor, is connected to a port
+func (b *Brick) IsMotor(port Port) bool {
+	return b.isCategory(port, DeviceCategoryMotor)
+}
+
+// IsSensor reports whether a sensor is connected to a port
+func (b *Brick) IsSensor(port Port) bool {
+	return b.isCategory(port, DeviceCategorySensor)
+}
+
+// isCategory reports whether a device of the given category is connected to a port
+func (b *Brick) isCategory(port Port, category DeviceCategory) bool {
+	typeID, connected := b.DeviceType(port)
+	return connected && getDeviceCategory(typeID) == category
+}
+
+// deviceInfo describes the device on a port. The caller must hold b.mu.
+func (b *Brick) deviceInfo(port Port) DeviceInfo {
+	conn := b.connections[port]
+	spec := getDeviceSpec(conn.TypeID)
+
+	return DeviceInfo{
+		Port:                port,
+		TypeID:              conn.TypeID,
+		Connected:           conn.Connected,
+		Name:                spec.Name,
+		Category:            spec.Category,
+		DefaultMode:         spec.DefaultMode,
+		HasAbsolutePosition: spec.HasAbsolutePosition,
+	}
+}
+
 // DeviceInfo represents information about a device
 type DeviceInfo struct {
-	Port      Port
-	TypeID    int
-	Connected bool
-	Name      string
-	Category  DeviceCategory
+	Port                Port           `json:"port"`
+	TypeID              int            `json:"type_id"`
+	Connected           bool           `json:"connected"`
+	Name                string         `json:"name"`
+	Category            DeviceCategory `json:"category"`
+	DefaultMode         int            `json:"default_mode"`
+	HasAbsolutePosition bool           `json:"has_absolute_position"`
 }
 
 // GetEmbeddedFirmwareVersion returns the version of the embedded firmware
@@ -536,7 +1810,58 @@ func (b *Brick) CheckFirmwareVersion() (bool, error) {
 	return b.firmwareManager.CheckFirmwareVersion()
 }
 
+// FirmwareIsAtLeast reports whether the running firmware is the embedded version or newer,
+// comparing numeric build IDs
+func (b *Brick) FirmwareIsAtLeast(embedded string) (bool, error) {
+	return b.firmwareManager.FirmwareIsAtLeast(embedded)
+}
+
+// FirmwareManager returns the firmware manager used by the brick, e.g. to tune transfer pacing
+func (b *Brick) FirmwareManager() *FirmwareManager {
+	return b.firmwareManager
+}
+
+// OnFirmwareProgress registers a callback invoked as a firmware update progresses
+func (b *Brick) OnFirmwareProgress(fn FirmwareProgressFunc) {
+	b.firmwareManager.progress = fn
+}
+
 // UpdateFirmware manually triggers a firmware update
 func (b *Brick) UpdateFirmware() error {
 	return b.firmwareManager.CheckAndUpdateFirmware()
 }
+
+// UpdateFirmwareFrom flashes the given firmware and signature images, bypassing the embedded ones.
+// Use this to install firmware newer than the bundled version.
+func (b *Brick) UpdateFirmwareFrom(firmware, signature []byte, version string) error {
+	return b.firmwareManager.UpdateFirmwareFrom(firmware, signature, version)
+}
+
+// EnterBootloader reboots the HAT and waits for the bootloader banner, e.g. to recover from an
+// interrupted firmware flash. If ctx has no deadline, a default timeout applies.
+func (b *Brick) EnterBootloader(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultBootloaderTimeout)
+		defer cancel()
+	}
+
+	matcher := b.addLineMatcher(func(line string) bool {
+		return strings.HasPrefix(line, bootloaderSignature)
+	})
+	defer b.removeLineMatcher(matcher)
+
+	if err := b.writeCommand(Reboot()); err != nil {
+		return fmt.Errorf("failed to reboot: %w", err)
+	}
+
+	select {
+	case line := <-matcher.future:
+		b.logger.Info("Entered bootloader", "banner", line)
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("bootloader did not respond: %w", ctx.Err())
+	case <-b.ctx.Done():
+		return fmt.Errorf("brick closed while waiting for bootloader")
+	}
+}
@@ -0,0 +1,62 @@
+package buildhat
+
+import (
+	"fmt"
+	"time"
+)
+
+// motorFor returns the motor interface for a port, creating and initializing it on first use
+func (b *Brick) motorFor(port Port) (*Motor, error) {
+	if !port.IsValid() {
+		return nil, fmt.Errorf("invalid port: %d", port)
+	}
+	return b.Motor(port), nil
+}
+
+// MoveMotorForDegrees runs the motor on a port for the specified number of degrees
+func (b *Brick) MoveMotorForDegrees(port Port, degrees, speed int) error {
+	motor, err := b.motorFor(port)
+	if err != nil {
+		return err
+	}
+	return motor.RunForDegrees(degrees, speed)
+}
+
+// MoveMotorForSeconds runs the motor on a port for the specified duration
+func (b *Brick) MoveMotorForSeconds(port Port, duration time.Duration, speed int) error {
+	motor, err := b.motorFor(port)
+	if err != nil {
+		return err
+	}
+	return motor.RunForDuration(duration, speed)
+}
+
+// MoveMotorToPosition runs the motor on a port to a position (in degrees) relative to its preset
+// position, in the frame set by Motor.SetPositionTo
+func (b *Brick) MoveMotorToPosition(port Port, position, speed int) error {
+	if speed < 0 || speed > 100 {
+		return fmt.Errorf("invalid speed: must be between 0 and 100")
+	}
+
+	motor, err := b.motorFor(port)
+	if err != nil {
+		return err
+	}
+
+	// position is in the SetPositionTo frame; the move itself is relative to the HAT's counter
+	current, err := motor.rawPosition()
+	if err != nil {
+		return err
+	}
+	target := position - int(motor.positionOffset.Load())
+	return motor.RunForDegrees(target-current, speed)
+}
+
+// MoveMotorToAbsolutePosition runs the motor on a port to an absolute position (in degrees, -180 to 180)
+func (b *Brick) MoveMotorToAbsolutePosition(port Port, position, speed int, direction MotorDirection) error {
+	motor, err := b.motorFor(port)
+	if err != nil {
+		return err
+	}
+	return motor.RunToPosition(position, speed, direction)
+}
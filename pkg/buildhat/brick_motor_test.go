@@ -0,0 +1,112 @@
+package buildhat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrick_MoveMotorForDegrees(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+
+	if err := brick.MoveMotorForDegrees(PortA, 360, 50); err != nil {
+		t.Fatalf("MoveMotorForDegrees failed: %v", err)
+	}
+
+	writeHistory := mockPort.GetWriteHistory()
+	expectedPrefix := "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set ramp 0.000000 1.000000"
+	if !slices.ContainsFunc(writeHistory, func(cmd string) bool { return strings.HasPrefix(cmd, expectedPrefix) }) {
+		t.Errorf("Expected ramp command with prefix %q, got: %v", expectedPrefix, writeHistory)
+	}
+	if last := mockPort.GetLastWrite(); last != "port 0 ; coast\r" {
+		t.Errorf("Expected final coast command, got %q", last)
+	}
+}
+
+func TestBrick_MoveMotorForSeconds(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+
+	if err := brick.MoveMotorForSeconds(PortB, 100*time.Millisecond, 50); err != nil {
+		t.Fatalf("MoveMotorForSeconds failed: %v", err)
+	}
+
+	expected := "port 1 ; select 0 ; selrate 10 ; pid 1 0 0 s1 1 0 0.003 0.01 0 100 0.01 ; set pulse 50.000000 0.0 0.100000 0\r"
+	if !slices.Contains(mockPort.GetWriteHistory(), expected) {
+		t.Errorf("Expected pulse command %q, got: %v", expected, mockPort.GetWriteHistory())
+	}
+}
+
+func TestBrick_MoveMotorToPosition(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("2", 0, "0 360 0")
+
+	// Initialize the motor first so the position reading is not consumed by setup
+	if _, err := brick.motorFor(PortC); err != nil {
+		t.Fatalf("motorFor failed: %v", err)
+	}
+
+	// Moving from 360 to 180 is a ramp from 1.0 down to 0.5 rotations
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mockPort.SimulateSensorResponse("2", 0, "0 360 0")
+	}()
+	if err := brick.MoveMotorToPosition(PortC, 180, 50); err != nil {
+		t.Fatalf("MoveMotorToPosition failed: %v", err)
+	}
+
+	expectedPrefix := "port 2 ; select 0 ; selrate 10 ; pid 2 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set ramp 1.000000 0.500000"
+	writeHistory := mockPort.GetWriteHistory()
+	if !slices.ContainsFunc(writeHistory, func(cmd string) bool { return strings.HasPrefix(cmd, expectedPrefix) }) {
+		t.Errorf("Expected ramp command with prefix %q, got: %v", expectedPrefix, writeHistory)
+	}
+}
+
+func TestBrick_MoveMotorToAbsolutePosition(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("3", 0, "0 0 0")
+
+	if err := brick.MoveMotorToAbsolutePosition(PortD, 90, 50, DirectionShortest); err != nil {
+		t.Fatalf("MoveMotorToAbsolutePosition failed: %v", err)
+	}
+
+	expectedPrefix := "port 3 ; select 0 ; selrate 10 ; pid 3 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set ramp 0.000000 0.250000"
+	writeHistory := mockPort.GetWriteHistory()
+	if !slices.ContainsFunc(writeHistory, func(cmd string) bool { return strings.HasPrefix(cmd, expectedPrefix) }) {
+		t.Errorf("Expected ramp command with prefix %q, got: %v", expectedPrefix, writeHistory)
+	}
+}
+
+func TestBrick_MoveMotor_ReusesMotor(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	first, err := brick.motorFor(PortA)
+	if err != nil {
+		t.Fatalf("motorFor failed: %v", err)
+	}
+	second, err := brick.motorFor(PortA)
+	if err != nil {
+		t.Fatalf("motorFor failed: %v", err)
+	}
+	if first != second {
+		t.Error("Expected the same motor instance to be reused for a port")
+	}
+
+	if _, err := brick.motorFor(Port(5)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
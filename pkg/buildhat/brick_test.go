@@ -1,9 +1,18 @@
 package buildhat
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestNewBrick(t *testing.T) {
@@ -123,6 +132,297 @@ func TestBrick_Close(t *testing.T) {
 	}
 }
 
+func TestBrick_Close_Concurrent(t *testing.T) {
+	brick := TestBrick(t)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := brick.Close(); err != nil {
+				t.Errorf("Close returned error: %v", err)
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := brick.ScanDevices(); err != nil && !errors.Is(err, ErrBrickClosed) {
+				t.Errorf("Expected nil or ErrBrickClosed racing Close, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := brick.ScanDevices(); !errors.Is(err, ErrBrickClosed) {
+		t.Errorf("Expected ErrBrickClosed after Close, got %v", err)
+	}
+}
+
+func TestBrick_SetTracer(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	var buf strings.Builder
+	brick.SetTracer(&buf)
+
+	brick.GetMockPort().RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+	if _, err := brick.GetVoltage(); err != nil {
+		t.Fatalf("GetVoltage failed: %v", err)
+	}
+	// Once cleared, nothing more is written, so the buffer can be read
+	brick.SetTracer(nil)
+	brick.ScanDevices()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 trace lines, got %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], " TX> vin") {
+		t.Errorf("Expected a TX line for vin, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " RX< 8.2 V") {
+		t.Errorf("Expected an RX line for the reply, got %q", lines[1])
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	input := "one\r\ntwo\rthree\nfour\r\rsix\r\n\r\neight"
+	expected := []string{"one", "two", "three", "four", "", "six", "", "eight"}
+
+	// Byte-at-a-time reads split every "\r\n" across two reads
+	for name, reader := range map[string]io.Reader{
+		"whole":        strings.NewReader(input),
+		"byte-by-byte": iotest.OneByteReader(strings.NewReader(input)),
+	} {
+		scanner := bufio.NewScanner(reader)
+		scanner.Split(splitLines())
+
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", expected) {
+			t.Errorf("%s: expected %q, got %q", name, expected, lines)
+		}
+	}
+}
+
+func TestBrick_MixedLineEndings(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	var trace strings.Builder
+	brick.SetTracer(&trace)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 30\r")
+	mockPort.QueueReadData("\nP1: connected to active ID 3D\rP2: connected to active ID 3E\n")
+	mockPort.QueueReadData("P3: connected to passive ID 1\r\n")
+	time.Sleep(50 * time.Millisecond)
+	brick.SetTracer(nil)
+
+	devices := brick.GetDeviceInfo()
+	for port, typeID := range map[Port]int{PortA: 0x30, PortB: 0x3D, PortC: 0x3E, PortD: 0x1} {
+		if devices[port].TypeID != typeID {
+			t.Errorf("Port %s: expected type %X, got %X", port, typeID, devices[port].TypeID)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 4 {
+		t.Errorf("Expected each of the 4 lines to be parsed once, got %q", trace.String())
+	}
+}
+
+func TestBrick_StopAll(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 30\r\n")
+	mockPort.QueueReadData("P1: connected to active ID 31\r\n")
+	mockPort.QueueReadData("P2: connected to passive ID 1\r\n")
+	mockPort.QueueReadData("P3: connected to active ID 41\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	// Movements blocked on their completion messages
+	ramp := pendingRamp{future: make(chan bool, 1), timeout: 10 * time.Second}
+	pulse := make(chan bool, 1)
+	brick.mu.Lock()
+	brick.rampFutures[PortA] = append(brick.rampFutures[PortA], ramp.future)
+	brick.pulseFutures[PortD] = append(brick.pulseFutures[PortD], pulse)
+	brick.mu.Unlock()
+
+	waited := make(chan error, 1)
+	go func() { waited <- ramp.wait() }()
+
+	mockPort.ClearWriteHistory()
+	if err := brick.StopAll(); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+
+	expected := "port 0 ; coast ; port 1 ; coast ; port 2 ; coast ; port 3 ; coast\r"
+	if history := mockPort.GetWriteHistory(); len(history) != 1 || history[0] != expected {
+		t.Errorf("Expected exact command %q, got %q", expected, history)
+	}
+
+	select {
+	case err := <-waited:
+		if !errors.Is(err, ErrStopped) {
+			t.Errorf("Expected ErrStopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pending ramp was not released")
+	}
+	if done, ok := <-pulse; done || ok {
+		t.Errorf("Expected the pulse future to be closed without a value, got %v, %v", done, ok)
+	}
+
+	brick.mu.RLock()
+	defer brick.mu.RUnlock()
+	for i := range NumPorts {
+		if len(brick.rampFutures[i]) != 0 || len(brick.pulseFutures[i]) != 0 {
+			t.Errorf("Port %d: expected futures to be drained", i)
+		}
+	}
+}
+
+func TestBrick_StopAll_SkipsSensors(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 3D\r\n")
+	mockPort.QueueReadData("P1: connected to active ID 30\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	mockPort.ClearWriteHistory()
+	if err := brick.StopAll(); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 1 || history[0] != "port 1 ; coast\r" {
+		t.Errorf("Expected only the motor port to coast, got %q", history)
+	}
+}
+
+func TestBrick_OnSensorData(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	readings := make(chan SensorReading, 4)
+	brick.OnSensorData(func(reading SensorReading) { readings <- reading })
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0M0: 10\r\n")
+	mockPort.QueueReadData("P2C0: 1.5 2.5\r\n")
+
+	seen := map[Port]SensorReading{}
+	for len(seen) < 2 {
+		select {
+		case reading := <-readings:
+			seen[reading.Port] = reading
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for readings, got %v", seen)
+		}
+	}
+	if r := seen[PortA]; r.Mode != 0 || r.Combi || len(r.Values) != 1 || r.Values[0] != 10 {
+		t.Errorf("Unexpected port A reading: %+v", r)
+	}
+	if r := seen[PortC]; !r.Combi || len(r.Values) != 2 || r.Values[1] != 2.5 {
+		t.Errorf("Unexpected port C reading: %+v", r)
+	}
+
+	// Removing the hook stops delivery
+	brick.OnSensorData(nil)
+	mockPort.QueueReadData("P1M0: 5\r\n")
+	select {
+	case reading := <-readings:
+		t.Errorf("Expected no reading after removing the hook, got %+v", reading)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBrick_OnPortChange(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	events := make(chan PortEvent, 4)
+	brick.OnPortChange(func(event PortEvent) { events <- event })
+
+	brick.parseLine("P1: connected to active ID 3D")
+	brick.parseLine("P1: disconnected")
+	brick.parseLine("P2: no device detected") // Nothing was connected, no event
+	brick.parseLine("P3: connected to passive ID 8")
+
+	expected := []PortEvent{
+		{Port: PortB, Connected: true, TypeID: 0x3d},
+		{Port: PortB, TypeID: -1},
+		{Port: PortD, Connected: true, TypeID: 8, Passive: true},
+	}
+	for i, want := range expected {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("Event %d: expected %+v, got %+v", i, want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event %d", i)
+		}
+	}
+
+	// Removing the hook stops delivery
+	brick.OnPortChange(nil)
+	brick.parseLine("P3: disconnected")
+	select {
+	case event := <-events:
+		t.Errorf("Expected no event after removing the hook, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBrick_OnSensorData_InOrder(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	const count = sensorHookBufferSize / 2
+	readings := make(chan SensorReading, count)
+	brick.OnSensorData(func(reading SensorReading) { readings <- reading })
+
+	for i := range count {
+		brick.handleSensorData(PortA, fmt.Sprintf("P0M0: %d", i))
+	}
+
+	for want := range count {
+		select {
+		case reading := <-readings:
+			if len(reading.Values) != 1 || reading.Values[0] != float64(want) {
+				t.Fatalf("Expected reading %d in order, got %v", want, reading.Values)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for reading %d", want)
+		}
+	}
+}
+
+func TestBrick_OnSensorData_SlowHookDoesNotBlockReader(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	release := make(chan struct{})
+	brick.OnSensorData(func(SensorReading) { <-release })
+	defer close(release)
+
+	mockPort := brick.GetMockPort()
+	for i := range sensorHookBufferSize + 10 {
+		mockPort.QueueReadData(fmt.Sprintf("P0M0: %d\r\n", i))
+	}
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+	if _, err := brick.GetVoltage(); err != nil {
+		t.Fatalf("Reader blocked behind a slow hook: %v", err)
+	}
+}
+
 func TestBrick_GetHardwareVersion(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -182,6 +482,131 @@ func TestBrick_GetVoltage(t *testing.T) {
 	}
 }
 
+func TestBrick_Request(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "7.9 V")
+
+	voltage, err := request(context.Background(), brick, Vin(), parseVoltageLine)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if voltage != 7.9 {
+		t.Errorf("Expected voltage 7.9, got %g", voltage)
+	}
+
+	// Timed out and cancelled requests unregister their wait too
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := request(ctx, brick, Version(), parseVersionLine); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.lineMatchers)
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected no pending waits, %d still registered", pending)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := request(context.Background(), brick, Version(), parseVersionLine)
+		done <- err
+	}()
+	for mockPort.GetWriteCount() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	brick.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrBrickClosed) {
+			t.Errorf("Expected ErrBrickClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Request did not return when the brick was closed")
+	}
+}
+
+func TestParseReplyLines(t *testing.T) {
+	if v, ok := parseVoltageLine("8.25 V"); !ok || v != 8.25 {
+		t.Errorf("parseVoltageLine(\"8.25 V\") = %g, %v", v, ok)
+	}
+	for _, line := range []string{"V", "8.25", "abc V", "P0: 8 V"} {
+		if _, ok := parseVoltageLine(line); ok {
+			t.Errorf("parseVoltageLine(%q) should fail", line)
+		}
+	}
+
+	tests := []struct {
+		line    string
+		version string
+	}{
+		{"Firmware version: 1737564117 2025-01-22T16:41:57+00:00", "1737564117 2025-01-22T16:41:57+00:00"},
+		{"BuildHAT bootloader version 1.0", "BuildHAT bootloader version 1.0"},
+	}
+	for _, tt := range tests {
+		if version, ok := parseVersionLine(tt.line); !ok || version != tt.version {
+			t.Errorf("parseVersionLine(%q) = %q, %v", tt.line, version, ok)
+		}
+	}
+	if _, ok := parseVersionLine("8.2 V"); ok {
+		t.Error("Expected a voltage line not to parse as a version")
+	}
+}
+
+func TestBrick_OnUnderVoltage(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+	brick.voltagePollInterval = 5 * time.Millisecond
+
+	mockPort := brick.GetMockPort()
+	mockPort.ClearWriteHistory()
+
+	var mu sync.Mutex
+	var edges []float64
+	stop := brick.OnUnderVoltage(7.0, func(v float64) {
+		mu.Lock()
+		edges = append(edges, v)
+		mu.Unlock()
+	})
+	defer stop()
+
+	// Answer each poll in turn: dip, stay low, recover within the hysteresis, then recover fully
+	readings := []string{"8.0 V", "6.9 V", "6.5 V", "7.1 V", "7.3 V", "7.4 V", "6.8 V"}
+	for i, reading := range readings {
+		deadline := time.Now().Add(time.Second)
+		for countCommands(mockPort.GetWriteHistory(), "vin\r") <= i {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for poll %d", i+1)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		mockPort.QueueReadData(reading + "\r\n")
+	}
+	stop()
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []float64{6.9, 7.3, 6.8}
+	if fmt.Sprint(edges) != fmt.Sprint(expected) {
+		t.Errorf("Expected edges %v, got %v", expected, edges)
+	}
+}
+
+func countCommands(history []string, cmd string) int {
+	count := 0
+	for _, c := range history {
+		if c == cmd {
+			count++
+		}
+	}
+	return count
+}
+
 func TestBrick_ScanDevices(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -233,6 +658,50 @@ func TestBrick_GetDeviceInfo(t *testing.T) {
 	}
 }
 
+func TestBrick_DeviceType(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.parseLine("P0: connected to active ID 4B") // Medium Angular Motor
+	brick.parseLine("P1: connected to active ID 3D") // Color Sensor
+	brick.parseLine("P2: connected to passive ID 1") // Passive motor
+
+	if typeID, connected := brick.DeviceType(PortA); typeID != 75 || !connected {
+		t.Errorf("Expected type 75 connected on port A, got %d %v", typeID, connected)
+	}
+	if typeID, connected := brick.DeviceType(PortD); typeID != -1 || connected {
+		t.Errorf("Expected nothing on port D, got %d %v", typeID, connected)
+	}
+	if _, connected := brick.DeviceType(Port(9)); connected {
+		t.Error("Expected invalid port to report no device")
+	}
+
+	tests := []struct {
+		port     Port
+		isMotor  bool
+		isSensor bool
+	}{
+		{PortA, true, false},
+		{PortB, false, true},
+		{PortC, false, false},
+		{PortD, false, false},
+	}
+	for _, tt := range tests {
+		if got := brick.IsMotor(tt.port); got != tt.isMotor {
+			t.Errorf("IsMotor(%s) = %v, want %v", tt.port, got, tt.isMotor)
+		}
+		if got := brick.IsSensor(tt.port); got != tt.isSensor {
+			t.Errorf("IsSensor(%s) = %v, want %v", tt.port, got, tt.isSensor)
+		}
+	}
+
+	// A disconnected motor no longer counts
+	brick.parseLine("P0: disconnected")
+	if brick.IsMotor(PortA) {
+		t.Error("Expected IsMotor to be false after disconnect")
+	}
+}
+
 func TestBrick_GetDeviceInfo_WithConnectedDevices(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -258,6 +727,9 @@ func TestBrick_GetDeviceInfo_WithConnectedDevices(t *testing.T) {
 	if portA.Category != DeviceCategoryMotor {
 		t.Errorf("Expected port A Category to be Motor, got %s", portA.Category)
 	}
+	if !portA.HasAbsolutePosition || portA.DefaultMode != 2 {
+		t.Errorf("Expected port A to report absolute position and default mode 2, got %v %d", portA.HasAbsolutePosition, portA.DefaultMode)
+	}
 
 	// Check port B (sensor)
 	portB := devices[PortB]
@@ -270,6 +742,9 @@ func TestBrick_GetDeviceInfo_WithConnectedDevices(t *testing.T) {
 	if portB.Category != DeviceCategorySensor {
 		t.Errorf("Expected port B Category to be Sensor, got %s", portB.Category)
 	}
+	if portB.HasAbsolutePosition || portB.DefaultMode != 5 {
+		t.Errorf("Expected port B default mode 5 without absolute position, got %v %d", portB.HasAbsolutePosition, portB.DefaultMode)
+	}
 }
 
 func TestBrick_GetEmbeddedFirmwareVersion(t *testing.T) {
@@ -426,3 +901,1101 @@ func TestBrick_tryParseVersionResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestBrick_WriteBytesToSensor(t *testing.T) {
+	tests := []struct {
+		name         string
+		port         Port
+		data         []byte
+		singleHeader bool
+		expected     string
+	}{
+		{"single header", PortA, []byte{0xc2, 0x01, 0x0a}, true, "port 0 ; write1 c2 1 a\r"},
+		{"double header", PortC, []byte{0xc2, 0x01, 0x0a}, false, "port 2 ; write2 c2 1 a\r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brick := TestBrick(t)
+			defer CleanupTestBrick(brick)
+
+			if err := brick.WriteBytesToSensor(tt.port, tt.data, tt.singleHeader); err != nil {
+				t.Fatalf("WriteBytesToSensor failed: %v", err)
+			}
+
+			lastWrite := brick.GetMockPort().GetLastWrite()
+			if lastWrite != tt.expected {
+				t.Errorf("Expected exact command %q, got %q", tt.expected, lastWrite)
+			}
+		})
+	}
+}
+
+func TestBrick_WriteBytesToSensor_InvalidPort(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.WriteBytesToSensor(Port(7), []byte{0x01}, true); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if count := brick.GetMockPort().GetWriteCount(); count != 0 {
+		t.Errorf("Expected no commands to be sent, got %d", count)
+	}
+}
+
+func TestBrick_SendRawCommand(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.SendRawCommand("  help\r\n"); err != nil {
+		t.Fatalf("SendRawCommand failed: %v", err)
+	}
+
+	lastWrite := brick.GetMockPort().GetLastWrite()
+	if lastWrite != "help\r" {
+		t.Errorf("Expected exact command %q, got %q", "help\r", lastWrite)
+	}
+
+	if err := brick.SendRawCommand("   "); err == nil {
+		t.Error("Expected error for empty raw command")
+	}
+}
+
+func TestBrick_SendRawCommandAwait(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	go func() {
+		for mockPort.GetWriteCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		mockPort.QueueReadData("help\r\n")
+		mockPort.QueueReadData("unrelated line\r\n")
+		mockPort.QueueReadData("help: this text\r\n")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := brick.SendRawCommandAwait(ctx, "help", func(line string) bool {
+		return strings.HasPrefix(line, "help:")
+	})
+	if err != nil {
+		t.Fatalf("SendRawCommandAwait failed: %v", err)
+	}
+	if reply != "help: this text" {
+		t.Errorf("Expected reply %q, got %q", "help: this text", reply)
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.lineMatchers)
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected matcher to be removed, %d still registered", pending)
+	}
+}
+
+func TestBrick_SendRawCommandAwait_Cancelled(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := brick.SendRawCommandAwait(ctx, "help", func(string) bool { return false })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.lineMatchers)
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected matcher to be removed, %d still registered", pending)
+	}
+}
+
+func TestBrick_EnterBootloader(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	go func() {
+		for mockPort.GetWriteCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		mockPort.SimulateFirmwareBootloader()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := brick.EnterBootloader(ctx); err != nil {
+		t.Fatalf("EnterBootloader failed: %v", err)
+	}
+
+	expected := Reboot().CommandString() + "\r"
+	if history := mockPort.GetWriteHistory(); len(history) == 0 || history[0] != expected {
+		t.Errorf("Expected %q to be sent, got %v", expected, history)
+	}
+}
+
+func TestBrick_EnterBootloader_Timeout(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := brick.EnterBootloader(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestBrick_RecoversFromReadError(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.InjectReadError(errors.New("line noise"), 0)
+
+	// Give the reader time to hit the error
+	time.Sleep(50 * time.Millisecond)
+
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+	voltage, err := brick.GetVoltage()
+	if err != nil {
+		t.Fatalf("Expected the reader to recover after a read error: %v", err)
+	}
+	if voltage != 8.2 {
+		t.Errorf("Expected voltage 8.2, got %f", voltage)
+	}
+}
+
+func TestBrick_OnDisconnect(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	disconnected := make(chan error, 1)
+	brick.OnDisconnect(func(err error) {
+		disconnected <- err
+	})
+
+	if err := brick.Err(); err != nil {
+		t.Fatalf("Expected no error while connected, got %v", err)
+	}
+
+	// Simulate the device going away underneath the brick
+	brick.GetMockPort().Close()
+
+	select {
+	case err := <-disconnected:
+		if err == nil {
+			t.Error("Expected disconnect callback to receive the read error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected disconnect callback to fire")
+	}
+
+	if brick.Err() == nil {
+		t.Error("Expected Err to report the read error")
+	}
+}
+
+func TestBrick_OnDisconnect_Reconnect(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	newPort := NewMockSerialPort(brick.logger)
+	reconnected := make(chan error, 1)
+	brick.OnDisconnect(func(error) {
+		reconnected <- brick.Reconnect(newPort, newPort)
+	})
+
+	brick.GetMockPort().Close()
+
+	select {
+	case err := <-reconnected:
+		if err != nil {
+			t.Fatalf("Reconnect from OnDisconnect failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Reconnect from OnDisconnect did not return")
+	}
+	if brick.GetMockPort() != newPort {
+		t.Error("Expected the brick to use the new port")
+	}
+	if err := brick.Err(); err != nil {
+		t.Errorf("Expected no error after reconnecting, got %v", err)
+	}
+}
+
+func TestBrick_Close_DoesNotReportDisconnect(t *testing.T) {
+	brick := TestBrick(t)
+
+	var fired bool
+	brick.OnDisconnect(func(error) { fired = true })
+	brick.Close()
+
+	if fired {
+		t.Error("Expected no disconnect callback on a deliberate Close")
+	}
+	if err := brick.Err(); err != nil {
+		t.Errorf("Expected no error after Close, got %v", err)
+	}
+}
+
+func TestBrick_Reconnect(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	oldPort := brick.GetMockPort()
+	newPort := NewMockSerialPort(brick.logger)
+	newPort.RespondTo(func(cmd string) bool { return cmd == "list" }, "P0: connected to active ID 4B")
+
+	if err := brick.Reconnect(newPort, newPort); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+
+	if brick.GetMockPort() != newPort {
+		t.Fatal("Expected the brick to use the new port")
+	}
+	if history := newPort.GetWriteHistory(); len(history) == 0 || history[0] != "list\r" {
+		t.Errorf("Expected device detection on the new port, got %v", history)
+	}
+	if _, err := oldPort.Write([]byte("x")); err == nil {
+		t.Error("Expected the old port to be closed")
+	}
+
+	// The restarted reader must process the list reply
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		brick.mu.RLock()
+		typeID := brick.connections[PortA].TypeID
+		brick.mu.RUnlock()
+		if typeID == 0x4B {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected port A to be detected after reconnect, got type %d", typeID)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBrick_Initialize_WithoutFirmwareUpdate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockPort := NewMockSerialPort(logger)
+	brick := NewBrick(mockPort, mockPort, logger, WithoutFirmwareUpdate())
+	defer CleanupTestBrick(brick)
+
+	mockPort.QueueReadData("Firmware version: 1737564117 2025-01-22T16:41:57+00:00\r\n")
+	mockPort.QueueReadData("P0: no device detected\r\n")
+
+	if err := brick.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Only the connection commands: no bootloader check or firmware transfer
+	expected := []string{"version\r", "list\r"}
+	if history := mockPort.GetWriteHistory(); !slices.Equal(history, expected) {
+		t.Errorf("Expected commands %q, got %q", expected, history)
+	}
+}
+
+func TestBrick_Initialize_WithInitialPowerLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockPort := NewMockSerialPort(logger)
+	brick := NewBrick(mockPort, mockPort, logger, WithoutFirmwareUpdate(), WithInitialPowerLimit(0.4))
+	defer CleanupTestBrick(brick)
+
+	mockPort.QueueReadData("Firmware version: 1737564117 2025-01-22T16:41:57+00:00\r\n")
+
+	if err := brick.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// The limit is applied before devices are scanned
+	expected := []string{"version\r", "plimit 0.4\r", "list\r"}
+	if history := mockPort.GetWriteHistory(); !slices.Equal(history, expected) {
+		t.Errorf("Expected commands %q, got %q", expected, history)
+	}
+
+}
+
+func TestBrick_Initialize_InvalidInitialPowerLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	for _, limit := range []float64{0, -0.5, 1.5} {
+		mockPort := NewMockSerialPort(logger)
+		brick := NewBrick(mockPort, mockPort, logger, WithoutFirmwareUpdate(), WithInitialPowerLimit(limit))
+
+		if err := brick.Initialize(); err == nil {
+			t.Errorf("Expected Initialize to reject power limit %g", limit)
+		}
+		if history := mockPort.GetWriteHistory(); len(history) != 0 {
+			t.Errorf("Expected no commands for power limit %g, got %q", limit, history)
+		}
+		CleanupTestBrick(brick)
+	}
+}
+
+func TestBrick_OverlongLineDiscarded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockPort := NewMockSerialPort(logger)
+	brick := NewBrick(mockPort, mockPort, logger, WithMaxLineSize(64))
+	defer CleanupTestBrick(brick)
+
+	// Binary garbage with no line terminator, far longer than the limit
+	mockPort.QueueReadData(strings.Repeat("\xff", 1000) + "\r\n")
+	time.Sleep(100 * time.Millisecond)
+
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+	voltage, err := brick.GetVoltage()
+	if err != nil {
+		t.Fatalf("Expected the reader to keep running after an overlong line: %v", err)
+	}
+	if voltage != 8.2 {
+		t.Errorf("Expected voltage 8.2, got %f", voltage)
+	}
+	if err := brick.Err(); err != nil {
+		t.Errorf("Expected an overlong line not to count as a disconnect, got %v", err)
+	}
+}
+
+func TestBrick_ConfigureCombi(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	if err := brick.ConfigureCombi(PortB, 1, NewModeDataset(0, 0), NewModeDataset(1, 0), NewModeDataset(3, 0)); err != nil {
+		t.Fatalf("ConfigureCombi failed: %v", err)
+	}
+	if err := brick.SelectCombi(PortB, 1); err != nil {
+		t.Fatalf("SelectCombi failed: %v", err)
+	}
+
+	expected := []string{
+		"port 1 ; combi 1 0 0 1 0 3 0\r",
+		"port 1 ; select 1 ; selrate 10\r",
+	}
+	history := mockPort.GetWriteHistory()
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d writes, got %v", len(expected), history)
+	}
+	for i, cmd := range expected {
+		if history[i] != cmd {
+			t.Errorf("Write %d: expected %q, got %q", i, cmd, history[i])
+		}
+	}
+}
+
+func TestBrick_ConfigureCombi_Invalid(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.ConfigureCombi(Port(9), 0, NewModeDataset(0, 0)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if err := brick.ConfigureCombi(PortA, -1, NewModeDataset(0, 0)); err == nil {
+		t.Error("Expected error for negative index")
+	}
+	if err := brick.ConfigureCombi(PortA, 0); err == nil {
+		t.Error("Expected error for no datasets")
+	}
+	if err := brick.SelectCombi(Port(9), 0); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if count := brick.GetMockPort().GetWriteCount(); count != 0 {
+		t.Errorf("Expected nothing written for invalid input, got %d writes", count)
+	}
+}
+
+func TestBrick_GetCommandHelp(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	go func() {
+		for mockPort.GetWriteCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		// Port traffic interleaved with the reply must not end up in the synopsis
+		mockPort.SimulateSensorResponse("A", 0, "12")
+		mockPort.SimulateHelpResponse()
+	}()
+
+	lines, err := brick.GetCommandHelp()
+	if err != nil {
+		t.Fatalf("GetCommandHelp failed: %v", err)
+	}
+	if len(lines) != len(mockHelpLines) {
+		t.Fatalf("Expected %d help lines, got %d: %v", len(mockHelpLines), len(lines), lines)
+	}
+	for i, line := range mockHelpLines {
+		if lines[i] != line {
+			t.Errorf("Line %d: expected %q, got %q", i, line, lines[i])
+		}
+	}
+
+	// Help output is a reply, not an unparseable line
+	if failures := brick.Metrics().ParseFailures; failures != 0 {
+		t.Errorf("Expected no parse failures for help output, got %d", failures)
+	}
+}
+
+func TestBrick_GetCommandHelp_NoPrompt(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.GetMockPort().RespondTo(func(cmd string) bool { return cmd == "help" },
+		"vin : print the input voltage", "list : list connected devices")
+
+	lines, err := brick.GetCommandHelp()
+	if err != nil {
+		t.Fatalf("GetCommandHelp failed: %v", err)
+	}
+	expected := []string{"vin : print the input voltage", "list : list connected devices"}
+	if len(lines) != len(expected) || lines[0] != expected[0] || lines[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestBrick_SetEcho_SkipsEchoedCommands(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	if err := brick.SetEcho(true); err != nil {
+		t.Fatalf("SetEcho failed: %v", err)
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 1 || history[0] != "echo 1\r" {
+		t.Fatalf("Expected echo command, got %v", history)
+	}
+
+	// The HAT echoes the command before replying
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "vin", "8.2 V")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := brick.SendRawCommandAwait(ctx, "vin", func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("SendRawCommandAwait failed: %v", err)
+	}
+	if reply != "8.2 V" {
+		t.Errorf("Expected the echoed command to be skipped, got %q", reply)
+	}
+}
+
+func TestBrick_SetEcho_Disabled(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.SetEcho(true); err != nil {
+		t.Fatalf("SetEcho failed: %v", err)
+	}
+	if err := brick.SetEcho(false); err != nil {
+		t.Fatalf("SetEcho failed: %v", err)
+	}
+
+	// With echo off, a line equal to a written command is an ordinary line
+	if err := brick.SendRawCommand("vin"); err != nil {
+		t.Fatalf("SendRawCommand failed: %v", err)
+	}
+	if brick.isEcho("vin") {
+		t.Error("Expected no echo tracking once echo is disabled")
+	}
+}
+
+func TestBrick_SetPortPowerLimit(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	tests := []struct {
+		port     Port
+		limit    float64
+		expected string
+	}{
+		{PortA, 0.5, "port 0 ; port_plimit 0.50\r"},
+		{PortD, 1, "port 3 ; port_plimit 1.00\r"},
+		{PortB, 0, "port 1 ; port_plimit 0.00\r"},
+	}
+
+	mockPort := brick.GetMockPort()
+	for _, tt := range tests {
+		if err := brick.SetPortPowerLimit(tt.port, tt.limit); err != nil {
+			t.Fatalf("SetPortPowerLimit(%s, %g) failed: %v", tt.port, tt.limit, err)
+		}
+		if lastCmd := mockPort.GetLastWrite(); lastCmd != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, lastCmd)
+		}
+	}
+
+	if err := brick.SetPortPowerLimit(PortA, 1.5); err == nil {
+		t.Error("Expected error for limit above 1")
+	}
+	if err := brick.SetPortPowerLimit(PortA, -0.1); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+	if err := brick.SetPortPowerLimit(Port(9), 0.5); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestBrick_SetMotorBias(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	if err := brick.SetMotorBias(PortC, 0.3); err != nil {
+		t.Fatalf("SetMotorBias failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 2 ; bias 0.3\r" {
+		t.Errorf("Expected %q, got %q", "port 2 ; bias 0.3\r", lastCmd)
+	}
+	if err := brick.SetBias(PortA, 0); err != nil {
+		t.Fatalf("SetBias failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 0 ; bias 0\r" {
+		t.Errorf("Expected %q, got %q", "port 0 ; bias 0\r", lastCmd)
+	}
+
+	mockPort.ClearWriteHistory()
+	for _, bias := range []float64{-0.1, 1.5} {
+		if err := brick.SetMotorBias(PortA, bias); err == nil {
+			t.Errorf("Expected error for bias %g", bias)
+		}
+	}
+	if err := brick.SetBias(Port(9), 0.5); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 0 {
+		t.Errorf("Expected no commands for rejected bias, got %v", history)
+	}
+}
+
+func TestBrick_MotorInitSetsBias(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	brick.Motor(PortA)
+	brick.PassiveMotor(PortB)
+
+	history := strings.Join(mockPort.GetWriteHistory(), "")
+	for _, expected := range []string{"port 0 ; bias 0.3\r", "port 1 ; bias 0\r"} {
+		if !strings.Contains(history, expected) {
+			t.Errorf("Expected initialization to send %q, got %q", expected, history)
+		}
+	}
+}
+
+func TestBrick_PortOnOff(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+
+	if err := brick.PortOn(PortC); err != nil {
+		t.Fatalf("PortOn failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 2 ; on\r" {
+		t.Errorf("Expected %q, got %q", "port 2 ; on\r", lastCmd)
+	}
+
+	if err := brick.PortOff(PortC); err != nil {
+		t.Fatalf("PortOff failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 2 ; off\r" {
+		t.Errorf("Expected %q, got %q", "port 2 ; off\r", lastCmd)
+	}
+
+	if err := brick.PortOn(Port(9)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if err := brick.PortOff(Port(9)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestBrick_Ports(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	ports := brick.Ports()
+	expected := []string{"A", "B", "C", "D"}
+	if len(ports) != len(expected) {
+		t.Fatalf("Expected %d ports, got %d", len(expected), len(ports))
+	}
+	for i, port := range ports {
+		if port.Int() != i {
+			t.Errorf("Ports()[%d] = %d, expected %d", i, port.Int(), i)
+		}
+		if got := fmt.Sprintf("%s", port); got != expected[i] {
+			t.Errorf("Ports()[%d] formats as %q, expected %q", i, got, expected[i])
+		}
+	}
+}
+
+func TestBrick_LatestSensorData(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if _, ok := brick.LatestSensorData(PortA); ok {
+		t.Error("Expected no cached data initially")
+	}
+
+	brick.handleSensorData(PortA, "P0M0: 12 34")
+
+	data, ok := brick.LatestSensorData(PortA)
+	if !ok {
+		t.Fatal("Expected cached data to be available")
+	}
+	if len(data) != 2 || data[0] != 12 || data[1] != 34 {
+		t.Errorf("Expected [12 34], got %v", data)
+	}
+
+	// Mutating the returned slice must not affect the cache
+	data[0] = 99
+
+	// Peeking must not consume the reading
+	consumed, err := brick.getSensorData(PortA)
+	if err != nil {
+		t.Fatalf("getSensorData failed: %v", err)
+	}
+	if consumed[0] != 12 {
+		t.Errorf("Expected consumed reading to start with 12, got %v", consumed[0])
+	}
+
+	if _, ok := brick.LatestSensorData(PortA); ok {
+		t.Error("Expected cache to be empty after consuming the reading")
+	}
+}
+
+func TestBrick_LatestSensorData_AfterDeliveredRead(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := brick.getSensorData(PortA)
+		result <- err
+	}()
+
+	// Deliver the reading to the waiting read rather than the cache
+	for {
+		brick.mu.RLock()
+		waiting := len(brick.sensorFutures[0])
+		brick.mu.RUnlock()
+		if waiting > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	brick.handleSensorData(PortA, "P0M0: 12 34")
+	if err := <-result; err != nil {
+		t.Fatalf("getSensorData failed: %v", err)
+	}
+
+	if data, ok := brick.LatestSensorData(PortA); ok {
+		t.Errorf("Expected no unconsumed reading after a read took it, got %v", data)
+	}
+}
+
+func TestBrick_LatestSensorData_Concurrent(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := range 200 {
+			brick.handleSensorData(PortB, fmt.Sprintf("P1M0: %d", i))
+		}
+		close(done)
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				brick.LatestSensorData(PortB)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				brick.mu.Lock()
+				brick.connections[PortB].Data = nil
+				brick.mu.Unlock()
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestBrick_selectModeAndRead(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	tests := []struct {
+		readOnce bool
+		expected string
+	}{
+		{true, "port 2 ; selonce 3\r"},
+		{false, "port 2 ; select 3\r"},
+	}
+	for _, tt := range tests {
+		mockPort.SimulateSensorResponse("C", 3, "7")
+		data, err := brick.selectModeAndRead(PortC, 3, tt.readOnce)
+		if err != nil {
+			t.Fatalf("selectModeAndRead(readOnce=%v) failed: %v", tt.readOnce, err)
+		}
+		if len(data) != 1 || data[0] != 7 {
+			t.Errorf("Expected reading [7], got %v", data)
+		}
+		if lastCmd := mockPort.GetLastWrite(); lastCmd != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, lastCmd)
+		}
+	}
+}
+
+func TestBrick_ReadModeValue(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	tests := []struct {
+		line     string
+		expected float64
+	}{
+		{"P0M2: -300", -300},
+		{"P0M2: d4 fe", -300},
+		{"P0M2: 2c 01", 300},
+	}
+	for _, tt := range tests {
+		brick.handleSensorData(PortA, tt.line)
+		value, err := brick.ReadModeValue(PortA, 2, 1, DataFormatS2)
+		if err != nil {
+			t.Fatalf("ReadModeValue(%q) failed: %v", tt.line, err)
+		}
+		if value != tt.expected {
+			t.Errorf("ReadModeValue(%q) = %v, expected %v", tt.line, value, tt.expected)
+		}
+		if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 0 ; selonce 2 1 s2\r" {
+			t.Errorf("Expected %q, got %q", "port 0 ; selonce 2 1 s2\r", lastCmd)
+		}
+	}
+
+	mockPort.ClearWriteHistory()
+	if _, err := brick.ReadModeValue(PortA, 2, 1, DataFormat("s3")); err == nil {
+		t.Error("Expected error for invalid format")
+	}
+	if _, err := brick.ReadModeValue(PortA, -1, 0, DataFormatS2); err == nil {
+		t.Error("Expected error for a negative mode")
+	}
+	if _, err := brick.ReadModeValue(Port(9), 2, 1, DataFormatS2); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if count := mockPort.GetWriteCount(); count != 0 {
+		t.Errorf("Expected no commands for invalid requests, got %d", count)
+	}
+}
+
+func TestDecodeDataFormat(t *testing.T) {
+	tests := []struct {
+		raw      []byte
+		format   DataFormat
+		expected float64
+	}{
+		{[]byte{0xff}, DataFormatU1, 255},
+		{[]byte{0xff}, DataFormatS1, -1},
+		{[]byte{0xd4, 0xfe}, DataFormatU2, 65236},
+		{[]byte{0xd4, 0xfe}, DataFormatS2, -300},
+		{[]byte{0xff, 0xff, 0xff, 0xff}, DataFormatS4, -1},
+		{[]byte{0x00, 0x00, 0x01, 0x00}, DataFormatU4, 65536},
+		{[]byte{0x00, 0x00, 0xc0, 0x3f}, DataFormatF4, 1.5},
+	}
+	for _, tt := range tests {
+		value, err := decodeDataFormat(tt.raw, tt.format)
+		if err != nil {
+			t.Fatalf("decodeDataFormat(% x, %s) failed: %v", tt.raw, tt.format, err)
+		}
+		if value != tt.expected {
+			t.Errorf("decodeDataFormat(% x, %s) = %v, expected %v", tt.raw, tt.format, value, tt.expected)
+		}
+	}
+
+	if _, err := decodeDataFormat([]byte{0x01}, DataFormatS2); err == nil {
+		t.Error("Expected error for a short payload")
+	}
+}
+
+func TestBrick_SelectCombiModesAndRead(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	tests := []struct {
+		readOnce bool
+		expected string
+	}{
+		{false, "port 1 ; combi 0 6 0 3 0 4 0 ; select 0\r"},
+		{true, "port 1 ; combi 0 6 0 3 0 4 0 ; selonce 0\r"},
+	}
+	for _, tt := range tests {
+		brick.handleSensorData(PortB, "P1C0: 9 40 12")
+		data, err := brick.SelectCombiModesAndRead(PortB, []int{6, 3, 4}, tt.readOnce)
+		if err != nil {
+			t.Fatalf("SelectCombiModesAndRead(readOnce=%v) failed: %v", tt.readOnce, err)
+		}
+		if len(data) != 3 || data[0] != 9 || data[1] != 40 || data[2] != 12 {
+			t.Errorf("Expected combi reading [9 40 12], got %v", data)
+		}
+		if lastCmd := mockPort.GetLastWrite(); lastCmd != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, lastCmd)
+		}
+	}
+
+	mockPort.ClearWriteHistory()
+	if _, err := brick.SelectCombiModesAndRead(PortB, nil, false); err == nil {
+		t.Error("Expected error for no modes")
+	}
+	if _, err := brick.SelectCombiModesAndRead(PortB, []int{1, -1}, false); err == nil {
+		t.Error("Expected error for a negative mode")
+	}
+	if _, err := brick.SelectCombiModesAndRead(Port(9), []int{1}, false); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+	if count := mockPort.GetWriteCount(); count != 0 {
+		t.Errorf("Expected no commands for invalid requests, got %d", count)
+	}
+}
+
+func TestBrick_ResetPort(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+
+	// A port with nothing configured still clears combi 0, which the package uses
+	if err := brick.ResetPort(PortA); err != nil {
+		t.Fatalf("ResetPort failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 0 ; select ; combi 0 ; coast\r" {
+		t.Errorf("Expected deselect, combi 0 deconfigure and coast, got %q", lastCmd)
+	}
+
+	// Every configured combi index is deconfigured
+	if err := brick.ConfigureCombi(PortC, 2, NewModeDataset(1, 0)); err != nil {
+		t.Fatalf("ConfigureCombi failed: %v", err)
+	}
+	if err := brick.ConfigureCombi(PortC, 0, NewModeDataset(2, 0)); err != nil {
+		t.Fatalf("ConfigureCombi failed: %v", err)
+	}
+	if err := brick.ResetPort(PortC); err != nil {
+		t.Fatalf("ResetPort failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 2 ; select ; combi 0 ; combi 2 ; coast\r" {
+		t.Errorf("Expected both combi indices to be deconfigured, got %q", lastCmd)
+	}
+
+	// Once reset, only the default index is cleared again
+	if err := brick.ResetPort(PortC); err != nil {
+		t.Fatalf("ResetPort failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 2 ; select ; combi 0 ; coast\r" {
+		t.Errorf("Expected a plain reset, got %q", lastCmd)
+	}
+
+	if err := brick.ResetPort(Port(9)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestBrick_SetSensorTimeout(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.SetSensorTimeout(PortC, 20*time.Millisecond); err != nil {
+		t.Fatalf("SetSensorTimeout failed: %v", err)
+	}
+
+	start := time.Now()
+	_, err := brick.ForceSensor(PortC).GetForce()
+	if !errors.Is(err, ErrSensorTimeout) {
+		t.Fatalf("Expected ErrSensorTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the port timeout to apply, read took %v", elapsed)
+	}
+	if !strings.Contains(err.Error(), "port 2") {
+		t.Errorf("Expected the error to name the port, got %q", err)
+	}
+
+	if got := brick.sensorTimeoutFor(PortD); got != brick.sensorTimeout {
+		t.Errorf("Expected other ports to keep the default timeout, got %v", got)
+	}
+	if err := brick.SetSensorTimeout(PortC, 0); err != nil {
+		t.Fatalf("SetSensorTimeout(0) failed: %v", err)
+	}
+	if got := brick.sensorTimeoutFor(PortC); got != brick.sensorTimeout {
+		t.Errorf("Expected zero to restore the default timeout, got %v", got)
+	}
+
+	if err := brick.SetSensorTimeout(PortC, -time.Second); err == nil {
+		t.Error("Expected error for a negative timeout")
+	}
+	if err := brick.SetSensorTimeout(Port(9), time.Second); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestBrick_getSensorData_WakesOnData(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	received := make(chan time.Time, 1)
+	go func() {
+		if _, err := brick.getSensorData(PortC); err == nil {
+			received <- time.Now()
+		}
+	}()
+
+	// Wait for the read to register its future, then deliver data
+	for {
+		brick.mu.RLock()
+		pending := len(brick.sensorFutures[PortC])
+		brick.mu.RUnlock()
+		if pending > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sent := time.Now()
+	brick.handleSensorData(PortC, "P2M0: 42")
+
+	select {
+	case at := <-received:
+		if latency := at.Sub(sent); latency > 50*time.Millisecond {
+			t.Errorf("Expected the read to complete as soon as data arrived, took %v", latency)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read did not complete after data arrived")
+	}
+}
+
+func TestBrick_getSensorData_TimeoutRemovesFuture(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.sensorTimeout = 20 * time.Millisecond
+
+	for range 3 {
+		if _, err := brick.getSensorData(PortC); err == nil {
+			t.Fatal("Expected timeout error")
+		}
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.sensorFutures[PortC])
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected no pending sensor futures after timeouts, got %d", pending)
+	}
+
+	// A reading arriving after the timeouts must be cached rather than dropped
+	brick.handleSensorData(PortC, "P2M0: 42")
+	data, ok := brick.LatestSensorData(PortC)
+	if !ok || data[0] != 42 {
+		t.Errorf("Expected cached reading [42], got %v", data)
+	}
+}
+
+func TestBrick_Subscribe(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	first, unsubscribeFirst := brick.Subscribe(PortA)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := brick.Subscribe(PortA)
+	defer unsubscribeSecond()
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "1")
+	mockPort.SimulateSensorResponse("0", 0, "2")
+	mockPort.SimulateSensorResponse("0", 0, "3")
+
+	for name, sub := range map[string]<-chan []any{"first": first, "second": second} {
+		for want := 1; want <= 3; want++ {
+			select {
+			case data := <-sub:
+				if len(data) != 1 || data[0] != want {
+					t.Errorf("%s subscriber: expected [%d], got %v", name, want, data)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s subscriber: timeout waiting for reading %d", name, want)
+			}
+		}
+	}
+}
+
+func TestBrick_Subscribe_OwnCopy(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	first, unsubscribeFirst := brick.Subscribe(PortA)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := brick.Subscribe(PortA)
+	defer unsubscribeSecond()
+
+	brick.handleSensorData(PortA, "P0M0: 7")
+
+	(<-first)[0] = -1
+	if data := <-second; data[0] != 7 {
+		t.Errorf("Expected the second subscriber to be unaffected, got %v", data)
+	}
+	brick.mu.RLock()
+	cached := brick.connections[0].Data
+	brick.mu.RUnlock()
+	if len(cached) != 1 || cached[0] != 7 {
+		t.Errorf("Expected the cached data to be unaffected, got %v", cached)
+	}
+}
+
+func TestBrick_Subscribe_Unsubscribe(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	sub, unsubscribe := brick.Subscribe(PortB)
+	unsubscribe()
+	unsubscribe() // Must be safe to call twice
+
+	if _, ok := <-sub; ok {
+		t.Error("Expected subscription channel to be closed")
+	}
+
+	brick.mu.RLock()
+	remaining := len(brick.subscribers[PortB])
+	brick.mu.RUnlock()
+	if remaining != 0 {
+		t.Errorf("Expected no subscribers after unsubscribe, got %d", remaining)
+	}
+
+	// Readings after unsubscribing must not panic on the closed channel
+	brick.handleSensorData(PortB, "P1M0: 5")
+}
@@ -6,10 +6,12 @@ import (
 
 // ButtonSensor creates a button sensor interface for the specified port
 func (b *Brick) ButtonSensor(port Port) *ButtonSensor {
-	return &ButtonSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *ButtonSensor {
+		return &ButtonSensor{
+			brick: b,
+			port:  port,
+		}
+	})
 }
 
 // ButtonSensor provides a Python-like button sensor interface
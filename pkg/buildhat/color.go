@@ -1,6 +1,12 @@
 package buildhat
 
-// Color represents an RGBA color value
+import (
+	"image/color"
+	"math"
+)
+
+// Color represents an RGBA color value. It implements image/color.Color, so readings can be
+// used directly with the standard library; see also ToRGBA and ColorFromRGBA.
 type Color struct {
 	R uint8 // Red component (0-255)
 	G uint8 // Green component (0-255)
@@ -8,6 +14,26 @@ type Color struct {
 	A uint8 // Alpha/Intensity component (0-255)
 }
 
+var _ color.Color = Color{}
+
+// RGBA implements image/color.Color. Sensors report intensity rather than transparency in A,
+// so the color is always opaque.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return c.ToRGBA().RGBA()
+}
+
+// ToRGBA converts the color to an opaque image/color.RGBA
+func (c Color) ToRGBA() color.RGBA {
+	return color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+}
+
+// ColorFromRGBA converts any image/color.Color to a Color, e.g. to compare a reading with a
+// reference color. A takes the color's alpha.
+func ColorFromRGBA(c color.Color) Color {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return Color{R: rgba.R, G: rgba.G, B: rgba.B, A: rgba.A}
+}
+
 // clamp8 ensures a value is within 0-255 range
 func clamp8(val int) uint8 {
 	if val < 0 {
@@ -18,3 +44,34 @@ func clamp8(val int) uint8 {
 	}
 	return uint8(val)
 }
+
+// HSV converts the RGB components to hue (0-360 degrees), saturation and value (0-1).
+// The intensity component A is ignored.
+func (c Color) HSV() (h, s, v float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	delta := maxC - minC
+
+	v = maxC
+	if maxC > 0 {
+		s = delta / maxC
+	}
+	if delta == 0 {
+		return 0, s, v
+	}
+
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
@@ -6,12 +6,21 @@ import (
 
 // ColorDistanceSensor creates a color distance sensor interface for the specified port
 func (b *Brick) ColorDistanceSensor(port Port) *ColorDistanceSensor {
-	return &ColorDistanceSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *ColorDistanceSensor {
+		return &ColorDistanceSensor{
+			brick: b,
+			port:  port,
+		}
+	})
 }
 
+// Color & distance sensor modes
+const (
+	colorDistanceModeReflected = 3 // REFLT: reflected light, 0-100
+	colorDistanceModeAmbient   = 4 // AMBI: ambient light, 0-100
+	colorDistanceModeLED       = 5 // COL O: LED color output
+)
+
 // ColorDistanceSensor provides a Python-like color distance sensor interface
 type ColorDistanceSensor struct {
 	brick *Brick
@@ -74,10 +83,19 @@ func (s *ColorDistanceSensor) GetDistance() (int, error) {
 	return 0, fmt.Errorf("invalid distance data type")
 }
 
-// GetReflectedLight gets the reflected light reading
+// GetReflectedLight gets the reflected light reading, with the LED lighting the surface
 func (s *ColorDistanceSensor) GetReflectedLight() (int, error) {
-	// Set to reflected light mode (mode 2)
-	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(2))); err != nil {
+	return s.getLight(colorDistanceModeReflected, "reflected")
+}
+
+// GetAmbientLight gets the ambient light reading
+func (s *ColorDistanceSensor) GetAmbientLight() (int, error) {
+	return s.getLight(colorDistanceModeAmbient, "ambient")
+}
+
+// getLight selects a light mode and returns its reading
+func (s *ColorDistanceSensor) getLight(mode int, kind string) (int, error) {
+	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(mode))); err != nil {
 		return 0, err
 	}
 
@@ -88,13 +106,28 @@ func (s *ColorDistanceSensor) GetReflectedLight() (int, error) {
 	}
 
 	if len(data) == 0 {
-		return 0, fmt.Errorf("no reflected light data received")
+		return 0, fmt.Errorf("no %s light data received", kind)
 	}
 
-	// Reflected light is the first value
+	// Light level is the first value
 	if light, ok := data[0].(int); ok {
 		return light, nil
 	}
 
-	return 0, fmt.Errorf("invalid reflected light data type")
+	return 0, fmt.Errorf("invalid %s light data type", kind)
+}
+
+// SetLED sets the color of the sensor's LED, using the same color indices as the matrix.
+// MatrixBlack turns it off.
+func (s *ColorDistanceSensor) SetLED(color MatrixColor) error {
+	if color < MatrixBlack || color > MatrixWhite {
+		return fmt.Errorf("color must be 0-10")
+	}
+
+	// Data message for the LED mode: header 0xC0 | mode, then the color
+	return s.brick.writeCommand(Compound(
+		SelectPort(s.port),
+		Select(colorDistanceModeLED),
+		Write1(0xc0|colorDistanceModeLED, byte(color)),
+	))
 }
@@ -64,7 +64,7 @@ func TestColorDistanceSensor_AllMethods(t *testing.T) {
 	}
 
 	// Test GetReflectedLight - queue response just before calling
-	mockPort.SimulateSensorResponse("0", 2, "85") // Reflected light
+	mockPort.SimulateSensorResponse("0", 3, "85") // Reflected light
 	time.Sleep(10 * time.Millisecond)             // Let reader process it
 	mockPort.ClearWriteHistory()                  // Clear previous commands
 	light, err := sensor.GetReflectedLight()
@@ -72,10 +72,10 @@ func TestColorDistanceSensor_AllMethods(t *testing.T) {
 		t.Fatalf("GetReflectedLight failed: %v", err)
 	}
 
-	// Verify EXACT command: "port 0 ; select 2\r" (mode 2 for reflected light)
+	// Verify EXACT command: "port 0 ; select 3\r" (mode 3 for reflected light)
 	writeHistory = mockPort.GetWriteHistory()
 	if len(writeHistory) > 0 {
-		expectedCmd := "port 0 ; select 2\r"
+		expectedCmd := "port 0 ; select 3\r"
 		if writeHistory[0] != expectedCmd {
 			t.Errorf("Expected exact command '%s', got: %s", expectedCmd, writeHistory[0])
 		}
@@ -115,3 +115,54 @@ func TestColorDistanceSensor_GetColor(t *testing.T) {
 		t.Errorf("Expected color %+v, got %+v", expected, color)
 	}
 }
+
+func TestColorDistanceSensor_GetAmbientLight(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("1", 4, "12")
+
+	sensor := brick.ColorDistanceSensor(PortB)
+	light, err := sensor.GetAmbientLight()
+	if err != nil {
+		t.Fatalf("GetAmbientLight failed: %v", err)
+	}
+	if light != 12 {
+		t.Errorf("Expected ambient light 12, got %d", light)
+	}
+
+	// Verify EXACT command: "port 1 ; select 4\r" (mode 4 for ambient light)
+	if last := mockPort.GetLastWrite(); last != "port 1 ; select 4\r" {
+		t.Errorf("Expected exact command 'port 1 ; select 4\\r', got: %s", last)
+	}
+}
+
+func TestColorDistanceSensor_SetLED(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ColorDistanceSensor(PortC)
+
+	tests := []struct {
+		color    MatrixColor
+		expected string
+	}{
+		{MatrixRed, "port 2 ; select 5 ; write1 c5 9\r"},
+		{MatrixWhite, "port 2 ; select 5 ; write1 c5 a\r"},
+		{MatrixBlack, "port 2 ; select 5 ; write1 c5 0\r"},
+	}
+	for _, tt := range tests {
+		if err := sensor.SetLED(tt.color); err != nil {
+			t.Fatalf("SetLED(%s) failed: %v", tt.color, err)
+		}
+		if last := mockPort.GetLastWrite(); last != tt.expected {
+			t.Errorf("SetLED(%s): expected exact command %q, got %q", tt.color, tt.expected, last)
+		}
+	}
+
+	if err := sensor.SetLED(MatrixColor(11)); err == nil {
+		t.Error("Expected error for an invalid color")
+	}
+}
@@ -2,13 +2,21 @@ package buildhat
 
 import (
 	"fmt"
+	"sync"
+	"time"
 )
 
 // ColorSensor creates a color sensor interface for the specified port
 func (b *Brick) ColorSensor(port Port) *ColorSensor {
+	return cachedDevice(b, port, func() *ColorSensor { return b.newColorSensor(port) })
+}
+
+// newColorSensor creates and initializes a ColorSensor interface
+func (b *Brick) newColorSensor(port Port) *ColorSensor {
 	cs := &ColorSensor{
-		brick: b,
-		port:  port,
+		brick:       b,
+		port:        port,
+		calibration: defaultColorCalibration,
 	}
 
 	// Initialize sensor like Python version does:
@@ -32,48 +40,285 @@ func (b *Brick) ColorSensor(port Port) *ColorSensor {
 type ColorSensor struct {
 	brick *Brick
 	port  Port
+
+	mu          sync.Mutex
+	calibration ColorCalibration
+
+	// Continuous color stream state, see StartColorStream
+	unsubscribe func()        // non-nil while streaming
+	ready       chan struct{} // closed once the stream delivered its first reading
+	raw         rgbi          // latest streamed reading
+
+	// live is the latest RGBI reading seen on the port, however it was requested, see Color
+	live    rgbi
+	liveSet bool
+}
+
+// rgbi is a raw mode 5 reading: red, green, blue and intensity, each 0-1024
+type rgbi [4]int
+
+// ColorCalibration holds raw R, G, B readings (0-1024) of white and black references.
+// GetColor maps the white reference to 255 and the black one to 0 on each channel.
+type ColorCalibration struct {
+	White [3]int
+	Black [3]int
+}
+
+// defaultColorCalibration maps the full raw range 0-1024 to 0-255
+var defaultColorCalibration = ColorCalibration{
+	White: [3]int{1024, 1024, 1024},
 }
 
-// GetColor gets the current color reading as RGBA
+// GetColor gets the current color reading as RGBA, from the RGBI mode (mode 5), with the
+// calibration applied to R, G and B. While a color stream is running it returns the latest
+// streamed color without sending commands.
 func (s *ColorSensor) GetColor() (Color, error) {
-	// Set to color RGB mode (mode 5 - RGBI)
-	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(5))); err != nil {
+	raw, err := s.readRGBI()
+	if err != nil {
 		return Color{}, err
 	}
 
-	// Wait for sensor data
-	data, err := s.brick.getSensorData(s.port)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calibration.apply(raw), nil
+}
+
+// Color returns the latest color seen on the port, with the calibration applied, without
+// sending any command. Any RGBI reading updates it, whether from GetColor, a color stream or
+// another reader of the port. ok is false until the first reading arrives.
+func (s *ColorSensor) Color() (color Color, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.liveSet {
+		return Color{}, false
+	}
+	return s.calibration.apply(s.live), true
+}
+
+// updateFromSensorData keeps Color up to date from RGBI readings on the port
+func (s *ColorSensor) updateFromSensorData(reading SensorReading) {
+	if reading.Combi || reading.Mode != 5 {
+		return
+	}
+	raw, err := parseRGBI(reading.data)
 	if err != nil {
-		return Color{}, err
+		return
 	}
 
-	if len(data) < 4 {
-		return Color{}, fmt.Errorf("insufficient color data received")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live = raw
+	s.liveSet = true
+}
+
+// CalibrateWhite captures the current reading as the white reference
+func (s *ColorSensor) CalibrateWhite() error {
+	raw, err := s.readRGBI()
+	if err != nil {
+		return err
 	}
 
-	// Convert from raw values (0-1024) to 0-255
-	r, _ := data[0].(int)
-	g, _ := data[1].(int)
-	b, _ := data[2].(int)
-	i, _ := data[3].(int)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	calibration := s.calibration
+	copy(calibration.White[:], raw[:3])
+	if err := calibration.validate(); err != nil {
+		return fmt.Errorf("white reference not brighter than black: %w", err)
+	}
+	s.calibration = calibration
+	return nil
+}
+
+// CalibrateBlack captures the current reading as the black reference
+func (s *ColorSensor) CalibrateBlack() error {
+	raw, err := s.readRGBI()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calibration := s.calibration
+	copy(calibration.Black[:], raw[:3])
+	if err := calibration.validate(); err != nil {
+		return fmt.Errorf("black reference not darker than white: %w", err)
+	}
+	s.calibration = calibration
+	return nil
+}
+
+// SetCalibration restores a calibration, e.g. one saved from GetCalibration
+func (s *ColorSensor) SetCalibration(calibration ColorCalibration) error {
+	if err := calibration.validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calibration = calibration
+	return nil
+}
+
+// GetCalibration returns the calibration in use
+func (s *ColorSensor) GetCalibration() ColorCalibration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calibration
+}
+
+// validate checks that white is brighter than black on every channel
+func (c ColorCalibration) validate() error {
+	for i := range c.White {
+		if c.White[i] <= c.Black[i] {
+			return fmt.Errorf("calibration channel %d: white %d must be greater than black %d", i, c.White[i], c.Black[i])
+		}
+	}
+	return nil
+}
+
+// apply converts a raw reading to a Color, scaling R, G and B between the references.
+// Intensity keeps the plain 0-1024 to 0-255 scaling.
+func (c ColorCalibration) apply(raw rgbi) Color {
+	channel := func(i int) uint8 {
+		return clamp8(((raw[i] - c.Black[i]) * 255) / (c.White[i] - c.Black[i]))
+	}
 	return Color{
-		R: clamp8((r * 255) / 1024),
-		G: clamp8((g * 255) / 1024),
-		B: clamp8((b * 255) / 1024),
-		A: clamp8((i * 255) / 1024),
-	}, nil
+		R: channel(0),
+		G: channel(1),
+		B: channel(2),
+		A: clamp8((raw[3] * 255) / 1024),
+	}
 }
 
-// GetReflectedLight gets the reflected light reading (0-100%)
-func (s *ColorSensor) GetReflectedLight() (int, error) {
-	// Set to reflected light mode (mode 1)
-	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(1))); err != nil {
-		return 0, err
+// readRGBI returns the latest streamed reading, or selects mode 5 and waits for a reading
+func (s *ColorSensor) readRGBI() (rgbi, error) {
+	if raw, ok, err := s.streamedRGBI(); ok {
+		return raw, err
+	}
+
+	// Read the color RGB mode (mode 5 - RGBI) once
+	data, err := s.brick.selectModeAndRead(s.port, 5, true)
+	if err != nil {
+		return rgbi{}, err
+	}
+
+	return parseRGBI(data)
+}
+
+// StartColorStream selects the RGBI mode once and keeps the latest color updated from the
+// readings the sensor streams, so GetColor doesn't re-select the mode on every call.
+// Reading reflected or ambient light re-selects another mode and interrupts the stream.
+func (s *ColorSensor) StartColorStream() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unsubscribe != nil {
+		return nil
+	}
+
+	readings, unsubscribe := s.brick.Subscribe(s.port)
+	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(5))); err != nil {
+		unsubscribe()
+		return err
+	}
+
+	s.unsubscribe = unsubscribe
+	s.ready = make(chan struct{})
+	go s.stream(readings, s.ready)
+	return nil
+}
+
+// StopColorStream stops updating the streamed color; GetColor reads on demand again
+func (s *ColorSensor) StopColorStream() {
+	s.mu.Lock()
+	unsubscribe := s.unsubscribe
+	s.unsubscribe = nil
+	s.mu.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// Close stops the color stream and deselects the sensor's mode so the HAT stops streaming
+// readings from the port. The sensor can still be read on demand afterwards.
+func (s *ColorSensor) Close() error {
+	s.StopColorStream()
+	return s.brick.writeCommand(Compound(SelectPort(s.port), SelectDeselect()))
+}
+
+// stream stores every RGBI reading until the subscription is closed
+func (s *ColorSensor) stream(readings <-chan []any, ready chan struct{}) {
+	first := true
+	for data := range readings {
+		raw, err := parseRGBI(data)
+		if err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		s.raw = raw
+		s.mu.Unlock()
+
+		if first {
+			close(ready)
+			first = false
+		}
+	}
+}
+
+// streamedRGBI returns the latest streamed reading, waiting for the first one if needed.
+// ok is false when no stream is running.
+func (s *ColorSensor) streamedRGBI() (raw rgbi, ok bool, err error) {
+	s.mu.Lock()
+	streaming, ready := s.unsubscribe != nil, s.ready
+	s.mu.Unlock()
+	if !streaming {
+		return rgbi{}, false, nil
+	}
+
+	select {
+	case <-ready:
+	case <-time.After(s.brick.sensorTimeoutFor(s.port)):
+		s.brick.metrics.timeouts.Add(1)
+		return rgbi{}, true, fmt.Errorf("no streamed color on port %s: %w", s.port, ErrSensorTimeout)
 	}
 
-	// Wait for sensor data
-	data, err := s.brick.getSensorData(s.port)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.raw, true, nil
+}
+
+// parseRGBI extracts a raw mode 5 reading
+func parseRGBI(data []any) (rgbi, error) {
+	if len(data) < 4 {
+		return rgbi{}, fmt.Errorf("insufficient color data received")
+	}
+
+	var raw rgbi
+	for i := range raw {
+		raw[i], _ = data[i].(int)
+	}
+	return raw, nil
+}
+
+// GetHSV gets the current color as hue (0-360 degrees), saturation and value (0-1),
+// converted from the RGB components of GetColor (mode 5)
+func (s *ColorSensor) GetHSV() (h, sat, v float64, err error) {
+	color, err := s.GetColor()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	h, sat, v = color.HSV()
+	return h, sat, v, nil
+}
+
+// GetReflectedLight gets the reflected light reading (0-100%)
+func (s *ColorSensor) GetReflectedLight() (int, error) {
+	// Read the reflected light mode (mode 1) once
+	data, err := s.brick.selectModeAndRead(s.port, 1, true)
 	if err != nil {
 		return 0, err
 	}
@@ -92,13 +337,8 @@ func (s *ColorSensor) GetReflectedLight() (int, error) {
 
 // GetAmbientLight gets the ambient light reading (0-100%)
 func (s *ColorSensor) GetAmbientLight() (int, error) {
-	// Set to ambient light mode (mode 2)
-	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(2))); err != nil {
-		return 0, err
-	}
-
-	// Wait for sensor data
-	data, err := s.brick.getSensorData(s.port)
+	// Read the ambient light mode (mode 2) once
+	data, err := s.brick.selectModeAndRead(s.port, 2, true)
 	if err != nil {
 		return 0, err
 	}
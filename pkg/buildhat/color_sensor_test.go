@@ -1,7 +1,9 @@
 package buildhat
 
 import (
+	"math"
 	"testing"
+	"time"
 )
 
 func TestColorSensor_GetColor(t *testing.T) {
@@ -30,8 +32,8 @@ func TestColorSensor_GetColor(t *testing.T) {
 	}
 
 	// First two commands are initialization: plimit and mode 6
-	// Third command should be the actual select mode 5 for RGBI
-	expectedCmd := "port 3 ; select 5\r"
+	// Third command should be a single read of mode 5 for RGBI
+	expectedCmd := "port 3 ; selonce 5\r"
 	selectCmd := writeHistory[2]
 	if selectCmd != expectedCmd {
 		t.Errorf("Expected exact command '%s', got: %s", expectedCmd, selectCmd)
@@ -45,6 +47,212 @@ func TestColorSensor_GetColor(t *testing.T) {
 	}
 }
 
+func TestColorSensor_GetHSV(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	// Simulate a pure blue-ish sample: R=0, G=512, B=1024 -> (0, 127, 255)
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("A", 5, "0 512 1024 1024")
+
+	sensor := brick.ColorSensor(PortA)
+
+	h, s, v, err := sensor.GetHSV()
+	if err != nil {
+		t.Fatalf("GetHSV failed: %v", err)
+	}
+
+	// Hue of (0, 127, 255) is 240 - 60*127/255 = 210.12 degrees
+	if math.Abs(h-210.12) > 0.01 {
+		t.Errorf("Expected hue 210.12, got %g", h)
+	}
+	if s != 1 {
+		t.Errorf("Expected saturation 1, got %g", s)
+	}
+	if v != 1 {
+		t.Errorf("Expected value 1, got %g", v)
+	}
+}
+
+func TestColorSensor_Color(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ColorSensor(PortB)
+	if _, ok := sensor.Color(); ok {
+		t.Fatal("Expected no color before any reading")
+	}
+	writes := mockPort.GetWriteCount()
+
+	// Other modes don't change the color
+	mockPort.SimulateSensorResponse("B", 1, "40")
+	mockPort.SimulateSensorResponse("B", 5, "512 256 768 1024")
+
+	expected := Color{R: 127, G: 63, B: 191, A: 255}
+	deadline := time.Now().Add(time.Second)
+	color, ok := sensor.Color()
+	for !ok && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		color, ok = sensor.Color()
+	}
+	if !ok || color != expected {
+		t.Errorf("Expected live color %+v, got %+v (ok=%v)", expected, color, ok)
+	}
+	if count := mockPort.GetWriteCount(); count != writes {
+		t.Errorf("Expected Color to send no commands, got %d writes", count-writes)
+	}
+}
+
+func TestColorSensor_Close(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ColorSensor(PortB)
+	if err := sensor.StartColorStream(); err != nil {
+		t.Fatalf("StartColorStream failed: %v", err)
+	}
+
+	if err := sensor.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if last := mockPort.GetLastWrite(); last != "port 1 ; select\r" {
+		t.Errorf("Expected exact command 'port 1 ; select\\r', got %q", last)
+	}
+
+	brick.mu.RLock()
+	subscribers := len(brick.subscribers[PortB])
+	brick.mu.RUnlock()
+	if subscribers != 0 {
+		t.Errorf("Expected Close to stop the color stream, got %d subscribers", subscribers)
+	}
+}
+
+func TestColorSensor_ColorStream(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ColorSensor(PortB)
+
+	if err := sensor.StartColorStream(); err != nil {
+		t.Fatalf("StartColorStream failed: %v", err)
+	}
+	// Starting twice must not select the mode again
+	if err := sensor.StartColorStream(); err != nil {
+		t.Fatalf("StartColorStream failed: %v", err)
+	}
+
+	writeHistory := mockPort.GetWriteHistory()
+	if len(writeHistory) != 3 || writeHistory[2] != "port 1 ; select 5\r" {
+		t.Fatalf("Expected init commands and a single select 5, got %v", writeHistory)
+	}
+
+	mockPort.SimulateSensorResponse("B", 5, "512 256 768 1024")
+	color, err := sensor.GetColor()
+	if err != nil {
+		t.Fatalf("GetColor failed: %v", err)
+	}
+	expected := Color{R: 127, G: 63, B: 191, A: 255}
+	if color != expected {
+		t.Errorf("Expected color %+v, got %+v", expected, color)
+	}
+
+	// Later readings update the cached color
+	mockPort.SimulateSensorResponse("B", 5, "1024 0 0 1024")
+	expected = Color{R: 255, G: 0, B: 0, A: 255}
+	deadline := time.Now().Add(time.Second)
+	for color != expected && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		color, _ = sensor.GetColor()
+	}
+	if color != expected {
+		t.Errorf("Expected streamed color %+v, got %+v", expected, color)
+	}
+
+	if count := mockPort.GetWriteCount(); count != 3 {
+		t.Errorf("Expected no commands while streaming, got %d writes", count)
+	}
+
+	// Once stopped, GetColor selects the mode again
+	sensor.StopColorStream()
+	mockPort.SimulateSensorResponse("B", 5, "0 0 1024 1024")
+	if _, err := sensor.GetColor(); err != nil {
+		t.Fatalf("GetColor failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 1 ; selonce 5\r" || mockPort.GetWriteCount() != 4 {
+		t.Errorf("Expected GetColor to select mode 5 after stopping, got %q", lastCmd)
+	}
+}
+
+func TestColorSensor_Calibration(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ColorSensor(PortC)
+
+	// Capture references under the current lighting
+	mockPort.SimulateSensorResponse("C", 5, "800 600 400 900")
+	if err := sensor.CalibrateWhite(); err != nil {
+		t.Fatalf("CalibrateWhite failed: %v", err)
+	}
+	mockPort.SimulateSensorResponse("C", 5, "100 50 20 100")
+	if err := sensor.CalibrateBlack(); err != nil {
+		t.Fatalf("CalibrateBlack failed: %v", err)
+	}
+
+	// The white reference maps to full scale
+	mockPort.SimulateSensorResponse("C", 5, "800 600 400 1024")
+	color, err := sensor.GetColor()
+	if err != nil {
+		t.Fatalf("GetColor failed: %v", err)
+	}
+	if color.R != 255 || color.G != 255 || color.B != 255 {
+		t.Errorf("Expected white to map to (255,255,255), got %+v", color)
+	}
+
+	// Halfway between black and white on each channel
+	mockPort.SimulateSensorResponse("C", 5, "450 325 210 1024")
+	color, err = sensor.GetColor()
+	if err != nil {
+		t.Fatalf("GetColor failed: %v", err)
+	}
+	expected := Color{R: 127, G: 127, B: 127, A: 255}
+	if color != expected {
+		t.Errorf("Expected %+v, got %+v", expected, color)
+	}
+
+	// A calibration can be saved and restored on another sensor
+	saved := sensor.GetCalibration()
+	expectedCalibration := ColorCalibration{White: [3]int{800, 600, 400}, Black: [3]int{100, 50, 20}}
+	if saved != expectedCalibration {
+		t.Errorf("Expected calibration %+v, got %+v", expectedCalibration, saved)
+	}
+	other := brick.ColorSensor(PortD)
+	if err := other.SetCalibration(saved); err != nil {
+		t.Fatalf("SetCalibration failed: %v", err)
+	}
+	if other.GetCalibration() != saved {
+		t.Error("Expected restored calibration to match")
+	}
+}
+
+func TestColorSensor_SetCalibration_Invalid(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	sensor := brick.ColorSensor(PortA)
+	invalid := ColorCalibration{White: [3]int{500, 500, 100}, Black: [3]int{100, 100, 100}}
+	if err := sensor.SetCalibration(invalid); err == nil {
+		t.Error("Expected error when white is not above black")
+	}
+	if sensor.GetCalibration() != defaultColorCalibration {
+		t.Error("Expected calibration to be unchanged after an invalid SetCalibration")
+	}
+}
+
 func TestColorSensor_GetReflectedLight(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -72,7 +280,7 @@ func TestColorSensor_GetReflectedLight(t *testing.T) {
 
 	// First two commands are initialization: plimit and mode 6
 	// Third command should be the actual select mode 1 for reflected light
-	expectedCmd := "port 3 ; select 1\r"
+	expectedCmd := "port 3 ; selonce 1\r"
 	selectCmd := writeHistory[2]
 	if selectCmd != expectedCmd {
 		t.Errorf("Expected exact command '%s', got: %s", expectedCmd, selectCmd)
@@ -111,7 +319,7 @@ func TestColorSensor_GetAmbientLight(t *testing.T) {
 
 	// First two commands are initialization: plimit and mode 6
 	// Third command should be the actual select mode 2 for ambient light
-	expectedCmd := "port 3 ; select 2\r"
+	expectedCmd := "port 3 ; selonce 2\r"
 	selectCmd := writeHistory[2]
 	if selectCmd != expectedCmd {
 		t.Errorf("Expected exact command '%s', got: %s", expectedCmd, selectCmd)
@@ -0,0 +1,57 @@
+package buildhat
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestColor_HSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		color   Color
+		h, s, v float64
+	}{
+		{"black", Color{0, 0, 0, 0}, 0, 0, 0},
+		{"white", Color{255, 255, 255, 255}, 0, 0, 1},
+		{"red", Color{255, 0, 0, 0}, 0, 1, 1},
+		{"green", Color{0, 255, 0, 0}, 120, 1, 1},
+		{"blue", Color{0, 0, 255, 0}, 240, 1, 1},
+		{"magenta", Color{255, 0, 255, 0}, 300, 1, 1},
+		{"half grey", Color{127, 127, 127, 0}, 0, 0, 127.0 / 255},
+		{"orange", Color{255, 128, 0, 0}, 30.1176, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, s, v := tt.color.HSV()
+			if math.Abs(h-tt.h) > 0.01 || math.Abs(s-tt.s) > 0.001 || math.Abs(v-tt.v) > 0.001 {
+				t.Errorf("HSV() = (%g, %g, %g), expected (%g, %g, %g)", h, s, v, tt.h, tt.s, tt.v)
+			}
+		})
+	}
+}
+
+func TestColor_StandardLibraryEquivalence(t *testing.T) {
+	c := Color{R: 200, G: 100, B: 50, A: 80}
+	std := color.RGBA{R: 200, G: 100, B: 50, A: 255}
+
+	if got := c.ToRGBA(); got != std {
+		t.Errorf("ToRGBA() = %+v, want %+v", got, std)
+	}
+
+	// Used as an image/color.Color, the reading is the same opaque color
+	var asStd color.Color = c
+	r1, g1, b1, a1 := asStd.RGBA()
+	r2, g2, b2, a2 := std.RGBA()
+	if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+		t.Errorf("RGBA() = %d %d %d %d, want %d %d %d %d", r1, g1, b1, a1, r2, g2, b2, a2)
+	}
+
+	if got := ColorFromRGBA(std); got != (Color{R: 200, G: 100, B: 50, A: 255}) {
+		t.Errorf("ColorFromRGBA(%+v) = %+v", std, got)
+	}
+	if got := ColorFromRGBA(color.NRGBA{R: 255, G: 0, B: 0, A: 255}); got != (Color{R: 255, A: 255}) {
+		t.Errorf("ColorFromRGBA(NRGBA red) = %+v", got)
+	}
+}
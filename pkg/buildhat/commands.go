@@ -2,6 +2,7 @@ package buildhat
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -95,6 +96,18 @@ func Signature() Command { return SignatureCommand{} }
 
 // ======== Simple Parameterized Commands ========
 
+// RawCommand sends an arbitrary protocol command string as-is
+type RawCommand struct {
+	command string
+}
+
+func (c *RawCommand) CommandString() string { return c.command }
+
+// Raw creates a command from a raw protocol string
+func Raw(command string) Command {
+	return &RawCommand{command: command}
+}
+
 // PortCommand sets the current port
 type PortCommand struct {
 	port Port
@@ -196,6 +209,30 @@ func Debug(code int) Command {
 
 // ======== Set Command (Setpoint Control) ========
 
+// Setpoint formatting policy:
+//   - constant and waveform setpoints (square, sine, triangle) print values with %g,
+//     the shortest exact form ("set 75", "set square 0 1 2 0");
+//   - timed setpoints (pulse, ramp) print values and durations with six decimals (%f),
+//     matching the output the motor code and firmware were validated against;
+//   - the pulse after-value always carries a decimal point ("0.0", never "0"),
+//     which the firmware requires in that position.
+//
+// These forms are pinned by TestSetpointFormattingPolicy.
+
+// formatTimed formats a pulse or ramp value or duration
+func formatTimed(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// formatWithDecimalPoint formats v like %g but always includes a decimal point
+func formatWithDecimalPoint(v float64) string {
+	str := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eEnN") {
+		str += ".0"
+	}
+	return str
+}
+
 // SetCommand configures the setpoint for a controller
 type SetCommand struct {
 	setpoint setpoint
@@ -258,12 +295,7 @@ type PulseSetpoint struct {
 }
 
 func (s *PulseSetpoint) String() string {
-	// Special case: format 0.0 as "0.0" not "0"
-	afterStr := fmt.Sprintf("%g", s.afterValue)
-	if s.afterValue == 0 {
-		afterStr = "0.0"
-	}
-	return fmt.Sprintf("pulse %f %s %f 0", s.duringValue, afterStr, s.duration)
+	return fmt.Sprintf("pulse %s %s %s 0", formatTimed(s.duringValue), formatWithDecimalPoint(s.afterValue), formatTimed(s.duration))
 }
 
 // RampSetpoint represents a ramp setpoint
@@ -274,7 +306,7 @@ type RampSetpoint struct {
 }
 
 func (s *RampSetpoint) String() string {
-	return fmt.Sprintf("ramp %f %f %f 0", s.startValue, s.endValue, s.duration)
+	return fmt.Sprintf("ramp %s %s %s 0", formatTimed(s.startValue), formatTimed(s.endValue), formatTimed(s.duration))
 }
 
 func (c SetCommand) CommandString() string {
@@ -583,6 +615,76 @@ func Compound(commands ...Command) Command {
 	return &CompoundCommand{commands: commands}
 }
 
+// ======== Command Builder ========
+
+// CommandBuilder chains commands into a compound command, e.g.
+//
+//	NewCommandBuilder().Port(PortA).Select(0).SelRate(10).PID(pid).SetRamp(0, 1, 2).Build()
+type CommandBuilder struct {
+	commands []Command
+}
+
+// NewCommandBuilder creates an empty command builder
+func NewCommandBuilder() *CommandBuilder {
+	return &CommandBuilder{}
+}
+
+// Then appends any command
+func (cb *CommandBuilder) Then(command Command) *CommandBuilder {
+	cb.commands = append(cb.commands, command)
+	return cb
+}
+
+// Port appends a port selection
+func (cb *CommandBuilder) Port(port Port) *CommandBuilder {
+	return cb.Then(SelectPort(port))
+}
+
+// Select appends a mode selection
+func (cb *CommandBuilder) Select(mode int) *CommandBuilder {
+	return cb.Then(Select(mode))
+}
+
+// SelRate appends a selection rate
+func (cb *CommandBuilder) SelRate(rate int) *CommandBuilder {
+	return cb.Then(SelRate(rate))
+}
+
+// PID appends a controller configuration built with PID or PIDDiff
+func (cb *CommandBuilder) PID(pid Command) *CommandBuilder {
+	return cb.Then(pid)
+}
+
+// SetConstant appends a constant setpoint
+func (cb *CommandBuilder) SetConstant(value float64) *CommandBuilder {
+	return cb.Then(SetConstant(value))
+}
+
+// SetConstantFormatted appends a constant setpoint printed with format
+func (cb *CommandBuilder) SetConstantFormatted(value float64, format string) *CommandBuilder {
+	return cb.Then(SetConstantFormatted(value, format))
+}
+
+// SetRamp appends a ramp setpoint
+func (cb *CommandBuilder) SetRamp(startValue, endValue, duration float64) *CommandBuilder {
+	return cb.Then(SetRamp(startValue, endValue, duration))
+}
+
+// SetPulse appends a pulse setpoint
+func (cb *CommandBuilder) SetPulse(duringValue, afterValue, duration float64) *CommandBuilder {
+	return cb.Then(SetPulse(duringValue, afterValue, duration))
+}
+
+// Coast appends a coast command
+func (cb *CommandBuilder) Coast() *CommandBuilder {
+	return cb.Then(Coast())
+}
+
+// Build returns the compound command
+func (cb *CommandBuilder) Build() Command {
+	return Compound(append([]Command(nil), cb.commands...)...)
+}
+
 // ======== Extended Commands (not in protocol.md but used in practice) ========
 
 // SelRateCommand sets the selection rate (frequency of sensor readings)
@@ -207,6 +207,41 @@ func TestSetWaveformCommands(t *testing.T) {
 	}
 }
 
+// TestSetpointFormattingPolicy pins the setpoint number formats documented in commands.go.
+// The firmware requires the decimal point in the pulse after-value.
+func TestSetpointFormattingPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  Command
+		expected string
+	}{
+		// Constants and waveforms use the shortest exact form
+		{"constant integer", SetConstant(75), "set 75"},
+		{"constant fraction", SetConstant(-0.25), "set -0.25"},
+		{"square", SetSquareWave(0, 0.8, 0.5, 0), "set square 0 0.8 0.5 0"},
+
+		// Timed setpoints use six decimals for values and durations
+		{"pulse", SetPulse(50, 0, 2.5), "set pulse 50.000000 0.0 2.500000 0"},
+		{"pulse negative", SetPulse(-0.3, 0, 1), "set pulse -0.300000 0.0 1.000000 0"},
+		{"ramp", SetRamp(0, 360, 3), "set ramp 0.000000 360.000000 3.000000 0"},
+		{"ramp fraction", SetRamp(-0.5, 1.25, 0.1), "set ramp -0.500000 1.250000 0.100000 0"},
+
+		// The pulse after-value always keeps its decimal point
+		{"pulse after zero", SetPulse(1, 0, 1), "set pulse 1.000000 0.0 1.000000 0"},
+		{"pulse after integer", SetPulse(1, 1, 1), "set pulse 1.000000 1.0 1.000000 0"},
+		{"pulse after negative", SetPulse(1, -2, 1), "set pulse 1.000000 -2.0 1.000000 0"},
+		{"pulse after fraction", SetPulse(1, 0.5, 1), "set pulse 1.000000 0.5 1.000000 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.command.CommandString(); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestPIDCommand(t *testing.T) {
 	cmd := PID(0, 0, 1, DataFormatS4, 0.0027777778, 0, 5, 0, 0.1, 3, 0.01)
 	expected := "pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01"
@@ -384,3 +419,30 @@ func TestComplexMotorCommand(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, result)
 	}
 }
+
+func TestCommandBuilder(t *testing.T) {
+	pid := PID(0, 0, 1, DataFormatS4, 0.0027777778, 0, 5, 0, 0.1, 3, 0.01)
+
+	built := NewCommandBuilder().Port(PortA).Select(0).SelRate(10).PID(pid).SetRamp(0, 360, 3).Build()
+	expected := Compound(SelectPort(PortA), Select(0), SelRate(10), pid, SetRamp(0, 360, 3))
+	if built.CommandString() != expected.CommandString() {
+		t.Errorf("Expected %q, got %q", expected.CommandString(), built.CommandString())
+	}
+	if got := built.CommandString(); got != "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set ramp 0.000000 360.000000 3.000000 0" {
+		t.Errorf("Unexpected ramp command: %q", got)
+	}
+
+	pulse := NewCommandBuilder().Port(PortB).Select(0).SelRate(10).PID(pid).SetPulse(50, 0, 2).Build()
+	if got, want := pulse.CommandString(), Compound(SelectPort(PortB), Select(0), SelRate(10), pid, SetPulse(50, 0, 2)).CommandString(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	constant := NewCommandBuilder().Port(PortC).SetConstantFormatted(0.5, "%f").Build()
+	if got := constant.CommandString(); got != "port 2 ; set 0.500000" {
+		t.Errorf("Unexpected constant command: %q", got)
+	}
+
+	if got := NewCommandBuilder().Port(PortD).Then(PortPLimit(0.5)).Coast().Build().CommandString(); got != "port 3 ; port_plimit 0.50 ; coast" {
+		t.Errorf("Unexpected coast command: %q", got)
+	}
+}
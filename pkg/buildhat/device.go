@@ -0,0 +1,119 @@
+package buildhat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoDevice is returned when a port is empty or holds another kind of device than requested
+var ErrNoDevice = errors.New("no such device")
+
+// Device returns an interface object matching the device currently detected on the port.
+// The result is one of *Motor, *PassiveMotor, *Light, *ColorSensor, *ColorDistanceSensor,
+// *DistanceSensor, *ForceSensor, *TiltSensor, *MotionSensor or *Matrix, or *PassiveSensor
+// for an unrecognised passive device.
+// ScanDevices (or Initialize) must have been called for the port to be detected.
+// Like the constructors it calls, it returns the same object for a port until the device
+// is unplugged.
+func (b *Brick) Device(port Port) (any, error) {
+	if !port.IsValid() {
+		return nil, fmt.Errorf("invalid port: %d", port)
+	}
+
+	b.mu.RLock()
+	conn := b.connections[port.Int()]
+	typeID, connected, passive := conn.TypeID, conn.Connected, conn.passive
+	b.mu.RUnlock()
+
+	if !connected {
+		return nil, fmt.Errorf("%w on port %s: nothing connected", ErrNoDevice, port)
+	}
+
+	spec := getDeviceSpec(typeID)
+	switch spec.Category {
+	case DeviceCategoryMotor:
+		return b.Motor(port), nil
+	case DeviceCategoryPassiveMotor:
+		return b.PassiveMotor(port), nil
+	case DeviceCategoryLight:
+		return b.Light(port), nil
+	case DeviceCategorySensor:
+		return b.sensorDevice(port, spec)
+	case DeviceCategoryUnknown:
+		if passive {
+			return b.PassiveSensor(port), nil
+		}
+		return nil, fmt.Errorf("unsupported device type %d on port %s", typeID, port)
+	default:
+		return nil, fmt.Errorf("unsupported device type %d on port %s", typeID, port)
+	}
+}
+
+// DeviceAs returns the device on the port as a T, such as *ColorSensor, after checking that
+// the HAT detected a device of that kind there. Unlike the constructors, which always return
+// an object, it fails with ErrNoDevice when the port is empty or holds another device, so
+// commands don't go unnoticed to an empty port:
+//
+//	sensor, err := buildhat.DeviceAs[*buildhat.ColorSensor](brick, buildhat.PortA)
+func DeviceAs[T any](b *Brick, port Port) (T, error) {
+	var zero T
+	device, err := b.Device(port)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := device.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w on port %s: found %T, want %T", ErrNoDevice, port, device, zero)
+	}
+	return typed, nil
+}
+
+// sensorDevice creates the sensor interface matching a sensor device spec
+func (b *Brick) sensorDevice(port Port, spec DeviceSpec) (any, error) {
+	switch spec.Name {
+	case "ColorSensor":
+		return b.ColorSensor(port), nil
+	case "ColorDistanceSensor":
+		return b.ColorDistanceSensor(port), nil
+	case "DistanceSensor":
+		return b.DistanceSensor(port), nil
+	case "ForceSensor":
+		return b.ForceSensor(port), nil
+	case "TiltSensor":
+		return b.TiltSensor(port), nil
+	case "MotionSensor":
+		return b.MotionSensor(port), nil
+	case "3x3 Color Light Matrix":
+		return b.Matrix(port), nil
+	default:
+		return nil, fmt.Errorf("unsupported sensor %q on port %s", spec.Name, port)
+	}
+}
+
+// cachedDevice returns the object of type T kept for the port, calling create on first use.
+// Repeated constructor calls so share one object, and its state, until the device is
+// unplugged or an object of another type is requested for the port. Invalid ports are not
+// cached.
+func cachedDevice[T any](b *Brick, port Port, create func() T) T {
+	if !port.IsValid() {
+		return create()
+	}
+
+	b.mu.RLock()
+	existing, ok := b.devices[port].(T)
+	b.mu.RUnlock()
+	if ok {
+		return existing
+	}
+
+	// Create outside the lock, constructors send initialization commands
+	device := create()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.devices[port].(T); ok {
+		return existing
+	}
+	b.devices[port] = device
+	return device
+}
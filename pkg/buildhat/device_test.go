@@ -0,0 +1,190 @@
+package buildhat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBrick_Device(t *testing.T) {
+	tests := []struct {
+		name   string
+		typeID int
+		check  func(any) bool
+	}{
+		{"motor", 75, func(d any) bool { _, ok := d.(*Motor); return ok }},
+		{"color sensor", 61, func(d any) bool { _, ok := d.(*ColorSensor); return ok }},
+		{"distance sensor", 62, func(d any) bool { _, ok := d.(*DistanceSensor); return ok }},
+		{"passive motor", 1, func(d any) bool { _, ok := d.(*PassiveMotor); return ok }},
+		{"light", 8, func(d any) bool { _, ok := d.(*Light); return ok }},
+		{"matrix", 64, func(d any) bool { _, ok := d.(*Matrix); return ok }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brick := TestBrick(t)
+			defer CleanupTestBrick(brick)
+
+			brick.handlePortMessage(PortA.Int(), fmt.Sprintf(": connected to active ID %02X", tt.typeID))
+
+			device, err := brick.Device(PortA)
+			if err != nil {
+				t.Fatalf("Device failed: %v", err)
+			}
+			if !tt.check(device) {
+				t.Errorf("Unexpected device type %T for type ID %d", device, tt.typeID)
+			}
+		})
+	}
+}
+
+func TestBrick_Device_EmptyPort(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.handlePortMessage(PortB.Int(), ": no device detected")
+
+	if _, err := brick.Device(PortB); err == nil {
+		t.Error("Expected error for empty port")
+	}
+}
+
+func TestDeviceAs(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.handlePortMessage(PortA.Int(), ": no device detected")
+	if _, err := DeviceAs[*ColorSensor](brick, PortA); !errors.Is(err, ErrNoDevice) {
+		t.Errorf("Expected ErrNoDevice for a color sensor on an empty port, got %v", err)
+	}
+
+	brick.handlePortMessage(PortA.Int(), ": connected to active ID 3D")
+	sensor, err := DeviceAs[*ColorSensor](brick, PortA)
+	if err != nil {
+		t.Fatalf("DeviceAs failed: %v", err)
+	}
+	if sensor != brick.ColorSensor(PortA) {
+		t.Error("Expected DeviceAs to return the cached color sensor")
+	}
+
+	// The wrong kind of device is reported the same way
+	if _, err := DeviceAs[*Motor](brick, PortA); !errors.Is(err, ErrNoDevice) {
+		t.Errorf("Expected ErrNoDevice for a motor on a color sensor port, got %v", err)
+	} else if !strings.Contains(err.Error(), "*buildhat.ColorSensor") {
+		t.Errorf("Expected the error to name the device found, got %q", err)
+	}
+
+	if _, err := DeviceAs[*ColorSensor](brick, Port(9)); err == nil || errors.Is(err, ErrNoDevice) {
+		t.Errorf("Expected an invalid port error, got %v", err)
+	}
+}
+
+func TestBrick_Device_UnknownPassive(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.handlePortMessage(PortD.Int(), ": connected to passive ID 5")
+
+	device, err := brick.Device(PortD)
+	if err != nil {
+		t.Fatalf("Device failed: %v", err)
+	}
+	if _, ok := device.(*PassiveSensor); !ok {
+		t.Errorf("Expected *PassiveSensor for an unknown passive device, got %T", device)
+	}
+
+	// Active devices of unknown type are still unsupported once the port changes
+	brick.handlePortMessage(PortD.Int(), ": connected to active ID 7F")
+	if _, err := brick.Device(PortD); err == nil {
+		t.Error("Expected error for unknown active device type")
+	}
+}
+
+func TestBrick_Device_UnknownType(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.handlePortMessage(PortC.Int(), ": connected to active ID 7F")
+
+	if _, err := brick.Device(PortC); err == nil {
+		t.Error("Expected error for unknown device type")
+	}
+}
+
+func TestBrick_DeviceCache(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	brick.parseLine("P0: connected to active ID 3D")
+
+	sensor := brick.ColorSensor(PortA)
+	if again := brick.ColorSensor(PortA); again != sensor {
+		t.Fatal("Expected repeated ColorSensor calls to return the same instance")
+	}
+	if device, err := brick.Device(PortA); err != nil || device != sensor {
+		t.Fatalf("Expected Device to return the cached sensor, got %v (%v)", device, err)
+	}
+	if initWrites := mockPort.GetWriteCount(); initWrites != 2 {
+		t.Errorf("Expected the sensor to be initialized once, got %d writes", initWrites)
+	}
+
+	// Data on port A updates the cached sensor, seen through any handle
+	if err := sensor.StartColorStream(); err != nil {
+		t.Fatalf("StartColorStream failed: %v", err)
+	}
+	mockPort.SimulateSensorResponse("A", 5, "1024 0 0 1024")
+	color, err := brick.ColorSensor(PortA).GetColor()
+	if err != nil {
+		t.Fatalf("GetColor failed: %v", err)
+	}
+	if expected := (Color{R: 255, G: 0, B: 0, A: 255}); color != expected {
+		t.Errorf("Expected streamed color %+v, got %+v", expected, color)
+	}
+
+	// Another type replaces the cached object, and unplugging drops it
+	if light := brick.Light(PortA); light == nil || brick.Light(PortA) != light {
+		t.Error("Expected a cached light after requesting another type")
+	}
+	brick.parseLine("P0: disconnected")
+	if fresh := brick.ColorSensor(PortA); fresh == sensor {
+		t.Error("Expected a new sensor after the device was unplugged")
+	}
+
+	if brick.Motor(PortB) == brick.Motor(PortC) {
+		t.Error("Expected separate instances per port")
+	}
+}
+
+func TestBrick_ConstructorsFromParsedPort(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	constructors := map[string]func(Port) any{
+		"Motor":               func(p Port) any { return brick.Motor(p) },
+		"PassiveMotor":        func(p Port) any { return brick.PassiveMotor(p) },
+		"Light":               func(p Port) any { return brick.Light(p) },
+		"Matrix":              func(p Port) any { return brick.Matrix(p) },
+		"ColorSensor":         func(p Port) any { return brick.ColorSensor(p) },
+		"ColorDistanceSensor": func(p Port) any { return brick.ColorDistanceSensor(p) },
+		"DistanceSensor":      func(p Port) any { return brick.DistanceSensor(p) },
+		"ForceSensor":         func(p Port) any { return brick.ForceSensor(p) },
+		"ButtonSensor":        func(p Port) any { return brick.ButtonSensor(p) },
+		"TiltSensor":          func(p Port) any { return brick.TiltSensor(p) },
+		"MotionSensor":        func(p Port) any { return brick.MotionSensor(p) },
+		"PassiveSensor":       func(p Port) any { return brick.PassiveSensor(p) },
+	}
+
+	for name, construct := range constructors {
+		for _, input := range []string{"C", "c", "2"} {
+			port, err := ParsePort(input)
+			if err != nil {
+				t.Fatalf("ParsePort(%q) failed: %v", input, err)
+			}
+			if got, want := construct(port), construct(PortC); got != want {
+				t.Errorf("%s(ParsePort(%q)) returned a different device than %s(PortC)", name, input, name)
+			}
+		}
+	}
+}
@@ -1,5 +1,14 @@
 package buildhat
 
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
 // DeviceCategory represents the category of a device
 type DeviceCategory int
 
@@ -30,60 +39,204 @@ func (d DeviceCategory) String() string {
 	}
 }
 
+// MarshalText encodes the category as its name, e.g. in JSON
+func (d DeviceCategory) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a category from the name written by MarshalText
+func (d *DeviceCategory) UnmarshalText(text []byte) error {
+	for category := DeviceCategoryUnknown; category <= DeviceCategoryLight; category++ {
+		if category.String() == string(text) {
+			*d = category
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid device category: %q", text)
+}
+
 // DeviceSpec contains the specification for a specific device type
 type DeviceSpec struct {
 	ID       int
 	Name     string
 	Category DeviceCategory
+
+	// DefaultMode is the mode holding the device's main reading, or -1 for output-only devices
+	DefaultMode int
+	// HasAbsolutePosition is set for motors reporting their absolute position (mode 3)
+	HasAbsolutePosition bool
 }
 
 // Known device types from the LEGO Powered Up specification
 var deviceRegistry = map[int]DeviceSpec{
 	// Passive Motors
-	1: {ID: 1, Name: "PassiveMotor", Category: DeviceCategoryPassiveMotor},
-	2: {ID: 2, Name: "PassiveMotor", Category: DeviceCategoryPassiveMotor},
+	1: {ID: 1, Name: "PassiveMotor", Category: DeviceCategoryPassiveMotor, DefaultMode: -1},
+	2: {ID: 2, Name: "PassiveMotor", Category: DeviceCategoryPassiveMotor, DefaultMode: -1},
 
 	// Lights
-	8: {ID: 8, Name: "Light", Category: DeviceCategoryLight},
+	8: {ID: 8, Name: "Light", Category: DeviceCategoryLight, DefaultMode: -1},
 
 	// Sensors
-	34: {ID: 34, Name: "TiltSensor", Category: DeviceCategorySensor},
-	35: {ID: 35, Name: "MotionSensor", Category: DeviceCategorySensor},
-	37: {ID: 37, Name: "ColorDistanceSensor", Category: DeviceCategorySensor},
-	61: {ID: 61, Name: "ColorSensor", Category: DeviceCategorySensor},
-	62: {ID: 62, Name: "DistanceSensor", Category: DeviceCategorySensor},
-	63: {ID: 63, Name: "ForceSensor", Category: DeviceCategorySensor},
-	64: {ID: 64, Name: "3x3 Color Light Matrix", Category: DeviceCategorySensor},
+	34: {ID: 34, Name: "TiltSensor", Category: DeviceCategorySensor, DefaultMode: 0},
+	35: {ID: 35, Name: "MotionSensor", Category: DeviceCategorySensor, DefaultMode: 0},
+	37: {ID: 37, Name: "ColorDistanceSensor", Category: DeviceCategorySensor, DefaultMode: 0},
+	61: {ID: 61, Name: "ColorSensor", Category: DeviceCategorySensor, DefaultMode: 5},
+	62: {ID: 62, Name: "DistanceSensor", Category: DeviceCategorySensor, DefaultMode: 0},
+	63: {ID: 63, Name: "ForceSensor", Category: DeviceCategorySensor, DefaultMode: 0},
+	64: {ID: 64, Name: "3x3 Color Light Matrix", Category: DeviceCategorySensor, DefaultMode: -1},
 
 	// Active Motors
-	38: {ID: 38, Name: "Medium Linear Motor", Category: DeviceCategoryMotor},
-	46: {ID: 46, Name: "Large Motor", Category: DeviceCategoryMotor},
-	47: {ID: 47, Name: "XL Motor", Category: DeviceCategoryMotor},
-	48: {ID: 48, Name: "Medium Angular Motor (Cyan)", Category: DeviceCategoryMotor},
-	49: {ID: 49, Name: "Large Angular Motor (Cyan)", Category: DeviceCategoryMotor},
-	65: {ID: 65, Name: "Small Angular Motor", Category: DeviceCategoryMotor},
-	75: {ID: 75, Name: "Medium Angular Motor (Grey)", Category: DeviceCategoryMotor},
-	76: {ID: 76, Name: "Large Angular Motor (Grey)", Category: DeviceCategoryMotor},
+	38: {ID: 38, Name: "Medium Linear Motor", Category: DeviceCategoryMotor, DefaultMode: 2},
+	46: {ID: 46, Name: "Large Motor", Category: DeviceCategoryMotor, DefaultMode: 2},
+	47: {ID: 47, Name: "XL Motor", Category: DeviceCategoryMotor, DefaultMode: 2},
+	48: {ID: 48, Name: "Medium Angular Motor (Cyan)", Category: DeviceCategoryMotor, DefaultMode: 2, HasAbsolutePosition: true},
+	49: {ID: 49, Name: "Large Angular Motor (Cyan)", Category: DeviceCategoryMotor, DefaultMode: 2, HasAbsolutePosition: true},
+	65: {ID: 65, Name: "Small Angular Motor", Category: DeviceCategoryMotor, DefaultMode: 2, HasAbsolutePosition: true},
+	75: {ID: 75, Name: "Medium Angular Motor (Grey)", Category: DeviceCategoryMotor, DefaultMode: 2, HasAbsolutePosition: true},
+	76: {ID: 76, Name: "Large Angular Motor (Grey)", Category: DeviceCategoryMotor, DefaultMode: 2, HasAbsolutePosition: true},
+}
+
+// Devices added by applications with RegisterDevice or OverrideDevice, consulted before the
+// built-in registry
+var (
+	customDevicesMu sync.RWMutex
+	customDevices   = map[int]DeviceSpec{}
+)
+
+// RegisterDevice adds a device type to the registry, e.g. a third-party sensor, so it's
+// reported with its name and category rather than as unknown. It's meant to be called at
+// init time and refuses to replace a built-in or already registered device; see OverrideDevice.
+func RegisterDevice(spec DeviceSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+	if builtIn, ok := deviceRegistry[spec.ID]; ok {
+		return fmt.Errorf("device type %d is built in (%s)", spec.ID, builtIn.Name)
+	}
+
+	customDevicesMu.Lock()
+	defer customDevicesMu.Unlock()
+	if registered, ok := customDevices[spec.ID]; ok {
+		return fmt.Errorf("device type %d is already registered (%s)", spec.ID, registered.Name)
+	}
+	customDevices[spec.ID] = spec
+	return nil
+}
+
+// OverrideDevice adds a device type to the registry like RegisterDevice, replacing any
+// built-in or registered device with the same ID
+func OverrideDevice(spec DeviceSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	customDevicesMu.Lock()
+	defer customDevicesMu.Unlock()
+	customDevices[spec.ID] = spec
+	return nil
+}
+
+// validate checks a spec can be added to the registry
+func (spec DeviceSpec) validate() error {
+	if spec.ID < 0 {
+		return fmt.Errorf("invalid device type ID: %d", spec.ID)
+	}
+	if spec.Name == "" {
+		return fmt.Errorf("device name must not be empty")
+	}
+	return nil
+}
+
+// lookupDeviceSpec returns the registered or built-in specification for a type ID
+func lookupDeviceSpec(typeID int) (DeviceSpec, bool) {
+	customDevicesMu.RLock()
+	spec, exists := customDevices[typeID]
+	customDevicesMu.RUnlock()
+	if exists {
+		return spec, true
+	}
+
+	spec, exists = deviceRegistry[typeID]
+	return spec, exists
+}
+
+// deviceAliases maps other common names, normalized with normalizeDeviceName, to type IDs
+var deviceAliases = map[int][]string{
+	1:  {"wedomotor"},
+	8:  {"led"},
+	37: {"colourdistancesensor"},
+	61: {"coloursensor"},
+	62: {"ultrasonicsensor"},
+	64: {"matrix", "lightmatrix"},
+	75: {"mediumangularmotor"},
+	76: {"largeangularmotor"},
+}
+
+// DeviceIDByName returns the type ID of a device from its name, e.g. "ColorSensor" or
+// "Medium Angular Motor (Grey)". Matching ignores case, spaces and punctuation, and accepts
+// a few aliases such as "colour sensor" or "light matrix". Names shared by several type IDs
+// return the lowest one. Device names, including registered ones, are matched before aliases.
+func DeviceIDByName(name string) (int, bool) {
+	key := normalizeDeviceName(name)
+	if key == "" {
+		return -1, false
+	}
+
+	customDevicesMu.RLock()
+	ids := slices.Collect(maps.Keys(customDevices))
+	customDevicesMu.RUnlock()
+	ids = append(ids, slices.Collect(maps.Keys(deviceRegistry))...)
+
+	id, found := -1, false
+	for _, specID := range ids {
+		spec, _ := lookupDeviceSpec(specID)
+		if normalizeDeviceName(spec.Name) == key && (!found || specID < id) {
+			id, found = specID, true
+		}
+	}
+	if found {
+		return id, true
+	}
+
+	for id, aliases := range deviceAliases {
+		if slices.Contains(aliases, key) {
+			return id, true
+		}
+	}
+	return -1, false
+}
+
+// normalizeDeviceName lowercases a device name and drops everything but letters and digits
+func normalizeDeviceName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
 }
 
 // getDeviceSpec returns the device specification for a type ID
 func getDeviceSpec(typeID int) DeviceSpec {
 	if typeID == -1 {
 		return DeviceSpec{
-			ID:       -1,
-			Name:     "Disconnected",
-			Category: DeviceCategoryDisconnected,
+			ID:          -1,
+			Name:        "Disconnected",
+			Category:    DeviceCategoryDisconnected,
+			DefaultMode: -1,
 		}
 	}
 
-	if spec, exists := deviceRegistry[typeID]; exists {
+	if spec, exists := lookupDeviceSpec(typeID); exists {
 		return spec
 	}
 
 	return DeviceSpec{
-		ID:       typeID,
-		Name:     "Unknown",
-		Category: DeviceCategoryUnknown,
+		ID:          typeID,
+		Name:        "Unknown",
+		Category:    DeviceCategoryUnknown,
+		DefaultMode: -1,
 	}
 }
 
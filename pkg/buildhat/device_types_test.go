@@ -282,3 +282,174 @@ func TestDeviceSpec_Consistency(t *testing.T) {
 		}
 	}
 }
+
+func TestGetDeviceSpec_ModesAndAbsolutePosition(t *testing.T) {
+	tests := []struct {
+		id                  int
+		defaultMode         int
+		hasAbsolutePosition bool
+	}{
+		{-1, -1, false},  // Disconnected
+		{999, -1, false}, // Unknown
+		{1, -1, false},   // Passive motor
+		{8, -1, false},   // Light
+		{61, 5, false},   // Color sensor reads RGBI
+		{62, 0, false},   // Distance sensor
+		{46, 2, false},   // Large motor has no absolute encoder
+		{48, 2, true},    // Medium angular motor
+		{75, 2, true},    // Medium angular motor (grey)
+	}
+
+	for _, tt := range tests {
+		spec := getDeviceSpec(tt.id)
+		if spec.DefaultMode != tt.defaultMode {
+			t.Errorf("ID %d: DefaultMode = %d, want %d", tt.id, spec.DefaultMode, tt.defaultMode)
+		}
+		if spec.HasAbsolutePosition != tt.hasAbsolutePosition {
+			t.Errorf("ID %d: HasAbsolutePosition = %v, want %v", tt.id, spec.HasAbsolutePosition, tt.hasAbsolutePosition)
+		}
+	}
+}
+
+func TestDeviceIDByName(t *testing.T) {
+	// Every registered name maps back to a type ID with that name
+	for id, spec := range deviceRegistry {
+		got, ok := DeviceIDByName(spec.Name)
+		if !ok {
+			t.Errorf("DeviceIDByName(%q) not found", spec.Name)
+			continue
+		}
+		if getDeviceName(got) != spec.Name {
+			t.Errorf("DeviceIDByName(%q) = %d (%q), want a device named like ID %d", spec.Name, got, getDeviceName(got), id)
+		}
+	}
+
+	tests := []struct {
+		name string
+		id   int
+		ok   bool
+	}{
+		{"ColorSensor", 61, true},
+		{"colorsensor", 61, true},
+		{"Color Sensor", 61, true},
+		{"colour_sensor", 61, true},
+		{"medium angular motor (grey)", 75, true},
+		{"Medium Angular Motor", 75, true},
+		{"3x3 Color Light Matrix", 64, true},
+		{"light matrix", 64, true},
+		{"PassiveMotor", 1, true}, // Shared by IDs 1 and 2
+		{"Ultrasonic Sensor", 62, true},
+		{"Disconnected", -1, false},
+		{"not a device", -1, false},
+		{"", -1, false},
+	}
+
+	for _, tt := range tests {
+		id, ok := DeviceIDByName(tt.name)
+		if id != tt.id || ok != tt.ok {
+			t.Errorf("DeviceIDByName(%q) = %d, %v, want %d, %v", tt.name, id, ok, tt.id, tt.ok)
+		}
+	}
+}
+
+// unregisterDevice removes a device added by a test from the registry
+func unregisterDevice(t *testing.T, id int) {
+	t.Cleanup(func() {
+		customDevicesMu.Lock()
+		delete(customDevices, id)
+		customDevicesMu.Unlock()
+	})
+}
+
+func TestRegisterDevice(t *testing.T) {
+	unregisterDevice(t, 200)
+	unregisterDevice(t, 61)
+
+	spec := DeviceSpec{ID: 200, Name: "Acme Light Sensor", Category: DeviceCategorySensor, DefaultMode: 0}
+	if err := RegisterDevice(spec); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	if got := getDeviceSpec(200); got != spec {
+		t.Errorf("Expected registered spec %+v, got %+v", spec, got)
+	}
+	if id, ok := DeviceIDByName("acme light sensor"); !ok || id != 200 {
+		t.Errorf("Expected name lookup to find 200, got %d %v", id, ok)
+	}
+
+	// A second registration for the same ID is refused rather than silently replacing the first
+	if err := RegisterDevice(DeviceSpec{ID: 200, Name: "Acme Other Sensor", Category: DeviceCategorySensor}); err == nil {
+		t.Error("Expected error registering an ID twice")
+	}
+	if got := getDeviceSpec(200); got != spec {
+		t.Errorf("Expected the first registration to be kept, got %+v", got)
+	}
+
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+	brick.parseLine("P2: connected to active ID C8")
+	info := brick.GetDeviceInfo()[PortC]
+	if info.Name != "Acme Light Sensor" || info.Category != DeviceCategorySensor {
+		t.Errorf("Expected GetDeviceInfo to report the registered device, got %+v", info)
+	}
+
+	// Built-ins are only replaced when forced
+	if err := RegisterDevice(DeviceSpec{ID: 61, Name: "Other", Category: DeviceCategorySensor}); err == nil {
+		t.Error("Expected error registering over a built-in device")
+	}
+	if getDeviceName(61) != "ColorSensor" {
+		t.Errorf("Expected built-in ColorSensor to be kept, got %q", getDeviceName(61))
+	}
+	if err := OverrideDevice(DeviceSpec{ID: 61, Name: "Other", Category: DeviceCategorySensor}); err != nil {
+		t.Fatalf("OverrideDevice failed: %v", err)
+	}
+	if getDeviceName(61) != "Other" {
+		t.Errorf("Expected overridden name, got %q", getDeviceName(61))
+	}
+
+	if err := RegisterDevice(DeviceSpec{ID: -1, Name: "Bad"}); err == nil {
+		t.Error("Expected error for negative ID")
+	}
+	if err := RegisterDevice(DeviceSpec{ID: 201}); err == nil {
+		t.Error("Expected error for empty name")
+	}
+}
+
+func TestDeviceIDByName_RegisteredNameBeforeAlias(t *testing.T) {
+	unregisterDevice(t, 202)
+
+	// "led" is an alias of the built-in light, but a device actually named LED wins
+	if err := RegisterDevice(DeviceSpec{ID: 202, Name: "LED", Category: DeviceCategoryLight, DefaultMode: -1}); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	if id, ok := DeviceIDByName("led"); !ok || id != 202 {
+		t.Errorf("Expected the registered LED (202), got %d %v", id, ok)
+	}
+	if id, ok := DeviceIDByName("wedo motor"); !ok || id != 1 {
+		t.Errorf("Expected aliases to still resolve, got %d %v", id, ok)
+	}
+}
+
+func TestDeviceCategory_TextRoundTrip(t *testing.T) {
+	categories := []DeviceCategory{
+		DeviceCategoryUnknown, DeviceCategoryDisconnected, DeviceCategoryMotor,
+		DeviceCategorySensor, DeviceCategoryPassiveMotor, DeviceCategoryLight,
+	}
+	for _, category := range categories {
+		text, err := category.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) failed: %v", category, err)
+		}
+		var decoded DeviceCategory
+		if err := decoded.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) failed: %v", text, err)
+		}
+		if decoded != category {
+			t.Errorf("Expected %v after a round trip, got %v", category, decoded)
+		}
+	}
+
+	var decoded DeviceCategory
+	if err := decoded.UnmarshalText([]byte("Robot")); err == nil {
+		t.Error("Expected error for an unknown category name")
+	}
+}
@@ -2,20 +2,55 @@ package buildhat
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 )
 
+// proximityHysteresis is how much further than the threshold, in millimeters, an object
+// must move before a proximity alarm clears
+const proximityHysteresis = 20
+
 // DistanceSensor creates a distance sensor interface for the specified port
 func (b *Brick) DistanceSensor(port Port) *DistanceSensor {
-	return &DistanceSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *DistanceSensor {
+		return &DistanceSensor{
+			brick:    b,
+			port:     port,
+			smoother: newMedianFilter(1),
+		}
+	})
 }
 
 // DistanceSensor provides a Python-like distance sensor interface
 type DistanceSensor struct {
 	brick *Brick
 	port  Port
+
+	mu       sync.Mutex
+	smoother *medianFilter  // filters GetDistance readings
+	watchers map[int]func() // stop functions of the running OnProximity watchers
+	nextID   int            // key of the next watcher
+}
+
+// SetSmoothing makes distances the median of the last window readings, rejecting the
+// occasional spike ultrasonic sensors produce. A window of 1, the default, disables it.
+// Changing the window discards the readings collected so far.
+func (s *DistanceSensor) SetSmoothing(window int) error {
+	if window < 1 {
+		return fmt.Errorf("smoothing window must be at least 1")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.smoother = newMedianFilter(window)
+	return nil
+}
+
+// newSmoother returns an empty filter with the current smoothing window
+func (s *DistanceSensor) newSmoother() *medianFilter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return newMedianFilter(s.smoother.window)
 }
 
 // GetDistance gets the current distance reading in millimeters
@@ -37,8 +72,122 @@ func (s *DistanceSensor) GetDistance() (int, error) {
 
 	// Distance is the first value
 	if distance, ok := data[0].(int); ok {
-		return distance, nil
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.smoother.add(distance), nil
 	}
 
 	return 0, fmt.Errorf("invalid distance data type")
 }
+
+// OnProximity streams distance readings and calls fn once when an object comes closer than
+// thresholdMM, and once more when it moves back beyond thresholdMM plus a small hysteresis
+// or out of range. Readings are smoothed as set by SetSmoothing when OnProximity is called.
+// fn is called from the streaming goroutine. Call the returned function to
+// stop watching.
+func (s *DistanceSensor) OnProximity(thresholdMM int, fn func(distance int)) (func(), error) {
+	if thresholdMM <= 0 {
+		return nil, fmt.Errorf("proximity threshold must be positive")
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("proximity callback must not be nil")
+	}
+
+	readings, unsubscribe := s.brick.Subscribe(s.port)
+	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(0))); err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	smoother := s.newSmoother()
+	stop := s.addWatcher(unsubscribe)
+	go func() {
+		near := false
+		for data := range readings {
+			if len(data) == 0 {
+				continue
+			}
+			distance, ok := data[0].(int)
+			if !ok {
+				continue
+			}
+			distance = smoother.add(distance)
+
+			// The sensor reports -1 when nothing is in range
+			switch {
+			case !near && distance >= 0 && distance < thresholdMM:
+				near = true
+				fn(distance)
+			case near && (distance < 0 || distance >= thresholdMM+proximityHysteresis):
+				near = false
+				fn(distance)
+			}
+		}
+	}()
+
+	return stop, nil
+}
+
+// addWatcher records a running watcher so Close can stop it, and returns its stop function
+func (s *DistanceSensor) addWatcher(unsubscribe func()) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watchers == nil {
+		s.watchers = make(map[int]func())
+	}
+	id := s.nextID
+	s.nextID++
+	s.watchers[id] = unsubscribe
+
+	return func() {
+		s.mu.Lock()
+		delete(s.watchers, id)
+		s.mu.Unlock()
+		unsubscribe()
+	}
+}
+
+// Close stops every OnProximity watcher and deselects the sensor's mode so the HAT stops
+// streaming readings from the port. The sensor can still be read on demand afterwards.
+func (s *DistanceSensor) Close() error {
+	s.mu.Lock()
+	watchers := s.watchers
+	s.watchers = nil
+	s.mu.Unlock()
+
+	for _, unsubscribe := range watchers {
+		unsubscribe()
+	}
+	return s.brick.writeCommand(Compound(SelectPort(s.port), SelectDeselect()))
+}
+
+// medianFilter is a ring buffer of the last readings that reports their median
+type medianFilter struct {
+	window  int
+	samples []int
+	next    int
+}
+
+func newMedianFilter(window int) *medianFilter {
+	return &medianFilter{window: window, samples: make([]int, 0, window)}
+}
+
+// add records a reading and returns the median of the readings held. With an even count
+// before the buffer fills, the lower middle value is used.
+func (f *medianFilter) add(value int) int {
+	if f.window <= 1 {
+		return value
+	}
+
+	if len(f.samples) < f.window {
+		f.samples = append(f.samples, value)
+	} else {
+		f.samples[f.next] = value
+	}
+	f.next = (f.next + 1) % f.window
+
+	sorted := slices.Clone(f.samples)
+	slices.Sort(sorted)
+	return sorted[(len(sorted)-1)/2]
+}
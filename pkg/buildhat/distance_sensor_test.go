@@ -1,7 +1,10 @@
 package buildhat
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDistanceSensor_GetDistance(t *testing.T) {
@@ -56,3 +59,142 @@ func TestDistanceSensor_AllPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestDistanceSensor_OnProximity(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.DistanceSensor(PortB)
+
+	var mu sync.Mutex
+	var edges []int
+	stop, err := sensor.OnProximity(100, func(distance int) {
+		mu.Lock()
+		edges = append(edges, distance)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("OnProximity failed: %v", err)
+	}
+	defer stop()
+
+	if last := mockPort.GetLastWrite(); last != "port 1 ; select 0\r" {
+		t.Errorf("Expected exact command 'port 1 ; select 0\\r', got %q", last)
+	}
+
+	// Approach, jitter inside the hysteresis band, retreat, approach again, then lose the object
+	for _, distance := range []int{300, 150, 99, 80, 105, 115, 60, 125, 90, -1} {
+		mockPort.QueueReadData(fmt.Sprintf("P1M0: %d\r\n", distance))
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []int{99, 125, 90, -1}
+	if fmt.Sprint(edges) != fmt.Sprint(expected) {
+		t.Errorf("Expected edges %v, got %v", expected, edges)
+	}
+}
+
+func TestDistanceSensor_Close(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.DistanceSensor(PortB)
+
+	var mu sync.Mutex
+	var edges []int
+	for range 2 {
+		if _, err := sensor.OnProximity(100, func(distance int) {
+			mu.Lock()
+			edges = append(edges, distance)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatalf("OnProximity failed: %v", err)
+		}
+	}
+
+	if err := sensor.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if last := mockPort.GetLastWrite(); last != "port 1 ; select\r" {
+		t.Errorf("Expected exact command 'port 1 ; select\\r', got %q", last)
+	}
+
+	brick.mu.RLock()
+	subscribers := len(brick.subscribers[PortB])
+	brick.mu.RUnlock()
+	if subscribers != 0 {
+		t.Errorf("Expected Close to remove every subscriber, got %d", subscribers)
+	}
+
+	brick.handleSensorData(PortB, "P1M0: 50")
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(edges) != 0 {
+		t.Errorf("Expected no proximity events after Close, got %v", edges)
+	}
+}
+
+func TestDistanceSensor_OnProximity_InvalidArgs(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	sensor := brick.DistanceSensor(PortA)
+	if _, err := sensor.OnProximity(0, func(int) {}); err == nil {
+		t.Error("Expected error for a zero threshold")
+	}
+	if _, err := sensor.OnProximity(100, nil); err == nil {
+		t.Error("Expected error for a nil callback")
+	}
+}
+
+func TestDistanceSensor_SetSmoothing(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.DistanceSensor(PortA)
+	if err := sensor.SetSmoothing(3); err != nil {
+		t.Fatalf("SetSmoothing failed: %v", err)
+	}
+
+	// The 900mm spike is rejected once the window has neighbours for it
+	var got []int
+	for _, raw := range []string{"150", "152", "900", "151", "153"} {
+		mockPort.SimulateSensorResponse("A", 0, raw)
+		distance, err := sensor.GetDistance()
+		if err != nil {
+			t.Fatalf("GetDistance failed: %v", err)
+		}
+		got = append(got, distance)
+	}
+	expected := []int{150, 150, 152, 152, 153}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected smoothed distances %v, got %v", expected, got)
+	}
+
+	// A window of 1 passes readings through
+	sensor.SetSmoothing(1)
+	mockPort.SimulateSensorResponse("A", 0, "900")
+	if distance, _ := sensor.GetDistance(); distance != 900 {
+		t.Errorf("Expected raw distance 900 without smoothing, got %d", distance)
+	}
+
+	if err := sensor.SetSmoothing(0); err == nil {
+		t.Error("Expected error for a zero window")
+	}
+}
+
+func TestMedianFilter(t *testing.T) {
+	filter := newMedianFilter(3)
+	for i, tc := range []struct{ in, out int }{{10, 10}, {500, 10}, {12, 12}, {11, 12}, {600, 12}, {700, 600}} {
+		if got := filter.add(tc.in); got != tc.out {
+			t.Errorf("Sample %d: expected median %d, got %d", i, tc.out, got)
+		}
+	}
+}
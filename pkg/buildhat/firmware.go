@@ -4,6 +4,7 @@ import (
 	"embed"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,18 +12,69 @@ import (
 //go:embed data/*
 var embeddedData embed.FS
 
+// Firmware update stages reported to the progress callback
+const (
+	FirmwareStageClear     = "clear"
+	FirmwareStageFirmware  = "firmware"
+	FirmwareStageSignature = "signature"
+	FirmwareStageReboot    = "reboot"
+)
+
+// bootloaderSignature prefixes the version reply and banner of the BuildHAT bootloader
+const bootloaderSignature = "BuildHAT bootloader version"
+
+// defaultBootloaderTimeout bounds EnterBootloader when the caller's context has no deadline
+const defaultBootloaderTimeout = 10 * time.Second
+
+// Default pacing of firmware transfers
+const (
+	defaultFirmwareChunkSize  = 1024
+	defaultFirmwareChunkDelay = 2 * time.Millisecond
+)
+
+// FirmwareProgressFunc receives firmware update progress.
+// stage is one of the FirmwareStage constants. Stages without a payload report 1 of 1 once done.
+type FirmwareProgressFunc func(stage string, bytesSent, total int)
+
 // FirmwareManager handles firmware updates for the BuildHat
 type FirmwareManager struct {
-	brick *Brick
+	brick      *Brick
+	progress   FirmwareProgressFunc
+	chunkSize  int           // bytes written per chunk during a transfer
+	chunkDelay time.Duration // pause between chunks so the HAT can keep up
+
+	// checksumTimeout bounds how long to wait for the HAT's checksum reply after a load
+	checksumTimeout time.Duration
 }
 
 // NewFirmwareManager creates a new firmware manager
 func NewFirmwareManager(brick *Brick) *FirmwareManager {
 	return &FirmwareManager{
-		brick: brick,
+		brick:           brick,
+		chunkSize:       defaultFirmwareChunkSize,
+		chunkDelay:      defaultFirmwareChunkDelay,
+		checksumTimeout: 2 * time.Second,
 	}
 }
 
+// SetChunkSize sets the number of bytes written per chunk during a transfer
+func (fm *FirmwareManager) SetChunkSize(size int) error {
+	if size <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+	fm.chunkSize = size
+	return nil
+}
+
+// SetChunkDelay sets the pause between chunks during a transfer
+func (fm *FirmwareManager) SetChunkDelay(delay time.Duration) error {
+	if delay < 0 {
+		return fmt.Errorf("chunk delay must not be negative")
+	}
+	fm.chunkDelay = delay
+	return nil
+}
+
 // CheckAndUpdateFirmware checks if firmware update is needed and performs it
 func (fm *FirmwareManager) CheckAndUpdateFirmware() error {
 	fm.brick.logger.Info("Checking firmware status")
@@ -39,8 +91,6 @@ func (fm *FirmwareManager) CheckAndUpdateFirmware() error {
 
 // isInBootloaderMode checks if the BuildHat is in bootloader mode
 func (fm *FirmwareManager) isInBootloaderMode() bool {
-	const bootloaderSignature = "BuildHAT bootloader version"
-
 	// Use GetHardwareVersion which properly uses futures
 	version, err := fm.brick.GetHardwareVersion()
 	if err != nil {
@@ -68,71 +118,171 @@ func (fm *FirmwareManager) updateFirmware() error {
 
 	fm.brick.logger.Info("Firmware loaded", "size", len(firmware), "signature_size", len(signature))
 
+	return fm.flash(firmware, signature)
+}
+
+// UpdateFirmwareFrom flashes caller-provided firmware and signature images instead of the embedded ones.
+// version is only used for logging; it should match the version the image reports once booted.
+func (fm *FirmwareManager) UpdateFirmwareFrom(firmware, signature []byte, version string) error {
+	if len(firmware) == 0 {
+		return fmt.Errorf("firmware image is empty")
+	}
+	if len(signature) == 0 {
+		return fmt.Errorf("firmware signature is empty")
+	}
+
+	fm.brick.logger.Info("Updating firmware from supplied image",
+		"version", version, "size", len(firmware), "signature_size", len(signature))
+
+	return fm.flash(firmware, signature)
+}
+
+// flash clears the HAT, loads the firmware and signature, then reboots it
+func (fm *FirmwareManager) flash(firmware, signature []byte) error {
 	// Step 1: Clear and get the prompt
 	if err := fm.brick.writeCommand(Clear()); err != nil {
 		return fmt.Errorf("failed to clear: %w", err)
 	}
 	time.Sleep(100 * time.Millisecond)
+	fm.reportProgress(FirmwareStageClear, 1, 1)
+
+	// Step 2: Load the firmware and check the HAT received it intact
+	if err := fm.loadFirmware(firmware); err != nil {
+		return err
+	}
+
+	// Step 3: Load the signature
+	if err := fm.brick.writeCommand(SignatureLoad(len(signature))); err != nil {
+		return fmt.Errorf("failed to load signature: %w", err)
+	}
+	time.Sleep(100 * time.Millisecond)
 
-	// Step 2: Load the firmware
+	if err := fm.transfer(FirmwareStageSignature, signature); err != nil {
+		return err
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Step 4: Reboot
+	if err := fm.brick.writeCommand(Reboot()); err != nil {
+		return fmt.Errorf("failed to reboot: %w", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Wait for boot to complete
+	time.Sleep(1500 * time.Millisecond)
+	fm.reportProgress(FirmwareStageReboot, 1, 1)
+
+	fm.brick.logger.Info("Firmware update completed successfully")
+	return nil
+}
+
+// loadFirmware sends the load command and firmware payload, then verifies the checksum the HAT reports
+func (fm *FirmwareManager) loadFirmware(firmware []byte) error {
 	checksum := fm.calculateChecksum(firmware)
 	if err := fm.brick.writeCommand(Load(len(firmware), int(checksum))); err != nil {
 		return fmt.Errorf("failed to load firmware: %w", err)
 	}
 	time.Sleep(100 * time.Millisecond)
 
-	// Send firmware data with STX/ETX markers
-	// STX
-	if _, err := fm.brick.writer.Write([]byte{0x02}); err != nil {
-		return fmt.Errorf("failed to write STX: %w", err)
+	// Listen for the checksum reply before sending so it cannot be missed. Any line mentioning
+	// the checksum is taken as the reply, so an unparseable one fails instead of being skipped.
+	matcher := fm.brick.addLineMatcher(func(line string) bool {
+		return strings.Contains(strings.ToLower(line), "checksum")
+	})
+	defer fm.brick.removeLineMatcher(matcher)
+
+	if err := fm.transfer(FirmwareStageFirmware, firmware); err != nil {
+		return err
 	}
-	if _, err := fm.brick.writer.Write(firmware); err != nil {
-		return fmt.Errorf("failed to write firmware: %w", err)
+
+	select {
+	case line := <-matcher.future:
+		reported, ok := parseChecksumReply(line)
+		if !ok {
+			return fmt.Errorf("unexpected firmware checksum reply from HAT: %q", line)
+		}
+		if reported != checksum {
+			return fmt.Errorf("firmware checksum mismatch: sent 0x%08x, HAT reported 0x%08x", checksum, reported)
+		}
+		fm.brick.logger.Info("Firmware checksum verified", "checksum", fmt.Sprintf("0x%08x", checksum))
+	case <-time.After(fm.checksumTimeout):
+		return fmt.Errorf("no firmware checksum reply from HAT after %v", fm.checksumTimeout)
 	}
-	// ETX
-	if _, err := fm.brick.writer.Write([]byte{0x03}); err != nil {
-		return fmt.Errorf("failed to write ETX: %w", err)
+
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+// parseChecksumReply extracts the checksum from a bootloader reply such as
+// "Checksum: 0x1d872b41" or "checksum 495397697". The value may be hex (0x prefix) or decimal.
+func parseChecksumReply(line string) (uint32, bool) {
+	idx := strings.Index(strings.ToLower(line), "checksum")
+	if idx < 0 {
+		return 0, false
 	}
-	// CR
-	if _, err := fm.brick.writer.Write([]byte("\r")); err != nil {
-		return fmt.Errorf("failed to write CR: %w", err)
+
+	fields := strings.Fields(strings.TrimLeft(line[idx+len("checksum"):], ": ="))
+	if len(fields) == 0 {
+		return 0, false
 	}
-	time.Sleep(10 * time.Millisecond)
 
-	// Step 3: Load the signature
-	if err := fm.brick.writeCommand(SignatureLoad(len(signature))); err != nil {
-		return fmt.Errorf("failed to load signature: %w", err)
+	value, err := strconv.ParseUint(fields[0], 0, 32)
+	if err != nil {
+		return 0, false
 	}
-	time.Sleep(100 * time.Millisecond)
+	return uint32(value), true
+}
 
-	// Send signature data with STX/ETX markers
+// transfer sends a payload framed by STX/ETX markers, in chunks, reporting progress
+func (fm *FirmwareManager) transfer(stage string, data []byte) error {
+	// STX
 	if _, err := fm.brick.writer.Write([]byte{0x02}); err != nil {
 		return fmt.Errorf("failed to write STX: %w", err)
 	}
-	if _, err := fm.brick.writer.Write(signature); err != nil {
-		return fmt.Errorf("failed to write signature: %w", err)
+
+	fm.reportProgress(stage, 0, len(data))
+	for sent := 0; sent < len(data); {
+		end := min(sent+fm.chunkSize, len(data))
+		if _, err := fm.brick.writer.Write(data[sent:end]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", stage, err)
+		}
+		sent = end
+		fm.reportProgress(stage, sent, len(data))
+
+		if err := fm.pace(); err != nil {
+			return fmt.Errorf("failed to drain %s: %w", stage, err)
+		}
 	}
+
+	// ETX
 	if _, err := fm.brick.writer.Write([]byte{0x03}); err != nil {
 		return fmt.Errorf("failed to write ETX: %w", err)
 	}
+	// CR
 	if _, err := fm.brick.writer.Write([]byte("\r")); err != nil {
 		return fmt.Errorf("failed to write CR: %w", err)
 	}
-	time.Sleep(10 * time.Millisecond)
+	return nil
+}
 
-	// Step 4: Reboot
-	if err := fm.brick.writeCommand(Reboot()); err != nil {
-		return fmt.Errorf("failed to reboot: %w", err)
+// pace waits for written data to be sent (when the writer supports draining) and pauses between chunks
+func (fm *FirmwareManager) pace() error {
+	if drainer, ok := fm.brick.writer.(interface{ Drain() error }); ok {
+		if err := drainer.Drain(); err != nil {
+			return err
+		}
 	}
-	time.Sleep(100 * time.Millisecond)
-
-	// Wait for boot to complete
-	time.Sleep(1500 * time.Millisecond)
-
-	fm.brick.logger.Info("Firmware update completed successfully")
+	time.Sleep(fm.chunkDelay)
 	return nil
 }
 
+// reportProgress invokes the progress callback if one is registered
+func (fm *FirmwareManager) reportProgress(stage string, bytesSent, total int) {
+	if fm.progress != nil {
+		fm.progress(stage, bytesSent, total)
+	}
+}
+
 // loadEmbeddedFile loads a file from the embedded filesystem
 func (fm *FirmwareManager) loadEmbeddedFile(path string) ([]byte, error) {
 	file, err := embeddedData.Open(path)
@@ -199,3 +349,37 @@ func (fm *FirmwareManager) CheckFirmwareVersion() (bool, error) {
 
 	return embeddedVersion == currentNumeric, nil
 }
+
+// FirmwareIsAtLeast reports whether the running firmware's build ID is embedded or newer.
+// Unlike CheckFirmwareVersion, firmware newer than the bundled one counts as up to date.
+func (fm *FirmwareManager) FirmwareIsAtLeast(embedded string) (bool, error) {
+	want, err := parseBuildID(embedded)
+	if err != nil {
+		return false, fmt.Errorf("invalid embedded version: %w", err)
+	}
+
+	currentVersion, err := fm.brick.GetHardwareVersion()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current version: %w", err)
+	}
+	current, err := parseBuildID(currentVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid current version: %w", err)
+	}
+
+	return current >= want, nil
+}
+
+// parseBuildID parses the numeric build ID leading a version string, e.g. 1737564117 in
+// "1737564117 2025-01-22T16:41:57+00:00"
+func parseBuildID(version string) (int64, error) {
+	fields := strings.Fields(version)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty version")
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no build ID in version %q", version)
+	}
+	return id, nil
+}
@@ -1,7 +1,14 @@
 package buildhat
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFirmwareManager_CheckFirmwareVersion(t *testing.T) {
@@ -27,6 +34,50 @@ func TestFirmwareManager_CheckFirmwareVersion(t *testing.T) {
 	}
 }
 
+func TestBrick_FirmwareIsAtLeast(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed string
+		embedded  string
+		expected  bool
+	}{
+		{"newer installed", "1737564200 2025-01-22T16:43:20+00:00", "1737564117", true},
+		{"same", "1737564117 2025-01-22T16:41:57+00:00", "1737564117", true},
+		{"older installed", "1700000000 2023-11-14T22:13:20+00:00", "1737564117", false},
+		{"more digits", "10000000000 2286-11-20T17:46:40+00:00", "9999999999", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brick := TestBrick(t)
+			defer CleanupTestBrick(brick)
+
+			brick.GetMockPort().QueueReadData("Firmware version: " + tt.installed + "\r\n")
+			upToDate, err := brick.FirmwareIsAtLeast(tt.embedded)
+			if err != nil {
+				t.Fatalf("FirmwareIsAtLeast failed: %v", err)
+			}
+			if upToDate != tt.expected {
+				t.Errorf("FirmwareIsAtLeast(%q) with %q = %v, want %v", tt.embedded, tt.installed, upToDate, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBrick_FirmwareIsAtLeast_InvalidVersions(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if _, err := brick.FirmwareIsAtLeast("not a version"); err == nil {
+		t.Error("Expected error for a non-numeric embedded version")
+	}
+
+	brick.GetMockPort().QueueReadData("BuildHAT bootloader version 1.0\r\n")
+	if _, err := brick.FirmwareIsAtLeast("1737564117"); err == nil {
+		t.Error("Expected error for a bootloader version")
+	}
+}
+
 func TestFirmwareManager_GetEmbeddedFirmwareVersion(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -159,3 +210,292 @@ func TestFirmwareManager_ChecksumCalculation(t *testing.T) {
 		t.Error("Same data should produce same checksum")
 	}
 }
+
+func TestFirmwareManager_UpdateProgress(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	type progressEvent struct {
+		stage     string
+		bytesSent int
+		total     int
+	}
+	var events []progressEvent
+	brick.OnFirmwareProgress(func(stage string, bytesSent, total int) {
+		events = append(events, progressEvent{stage, bytesSent, total})
+	})
+
+	firmware, err := brick.firmwareManager.loadEmbeddedFile("data/firmware.bin")
+	if err != nil {
+		t.Fatalf("Failed to load embedded firmware: %v", err)
+	}
+	replyChecksum(brick, firmware)
+
+	if err := brick.firmwareManager.updateFirmware(); err != nil {
+		t.Fatalf("updateFirmware failed: %v", err)
+	}
+
+	// Stages must appear in order
+	var stages []string
+	for _, e := range events {
+		if len(stages) == 0 || stages[len(stages)-1] != e.stage {
+			stages = append(stages, e.stage)
+		}
+	}
+	expectedStages := []string{FirmwareStageClear, FirmwareStageFirmware, FirmwareStageSignature, FirmwareStageReboot}
+	if !slices.Equal(stages, expectedStages) {
+		t.Fatalf("Expected stages %v, got %v", expectedStages, stages)
+	}
+
+	// Progress within each stage must be monotonic and reach 100%
+	last := map[string]progressEvent{}
+	for _, e := range events {
+		if prev, ok := last[e.stage]; ok && e.bytesSent < prev.bytesSent {
+			t.Errorf("Progress for %s went backwards: %d after %d", e.stage, e.bytesSent, prev.bytesSent)
+		}
+		last[e.stage] = e
+	}
+	for _, stage := range expectedStages {
+		if e := last[stage]; e.bytesSent != e.total || e.total == 0 {
+			t.Errorf("Expected %s to finish at 100%%, got %d/%d", stage, e.bytesSent, e.total)
+		}
+	}
+
+	// The firmware payload is larger than one chunk, so it must report intermediate progress
+	firmwareEvents := 0
+	for _, e := range events {
+		if e.stage == FirmwareStageFirmware {
+			firmwareEvents++
+		}
+	}
+	if firmwareEvents < 3 {
+		t.Errorf("Expected multiple firmware progress events, got %d", firmwareEvents)
+	}
+}
+
+// chunkRecorder is a writer that records the size of every write
+type chunkRecorder struct {
+	mu     sync.Mutex
+	writes [][]byte
+	drains int
+}
+
+func (r *chunkRecorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes = append(r.writes, append([]byte(nil), data...))
+	return len(data), nil
+}
+
+func (r *chunkRecorder) Drain() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drains++
+	return nil
+}
+
+// replyChecksum makes the mock answer the ETX closing a payload with the checksum of firmware
+func replyChecksum(brick *Brick, firmware []byte) {
+	reply := fmt.Sprintf("Checksum: 0x%08x", brick.firmwareManager.calculateChecksum(firmware))
+	brick.GetMockPort().RespondTo(func(cmd string) bool { return cmd == "\x03" }, reply)
+}
+
+func TestFirmwareManager_TransferChunking(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	recorder := &chunkRecorder{}
+	brick := NewBrick(NewMockSerialPort(logger), recorder, logger)
+	defer brick.Close()
+
+	fm := brick.firmwareManager
+	if err := fm.SetChunkSize(0); err == nil {
+		t.Error("Expected error for zero chunk size")
+	}
+	if err := fm.SetChunkSize(4); err != nil {
+		t.Fatalf("SetChunkSize failed: %v", err)
+	}
+	if err := fm.SetChunkDelay(0); err != nil {
+		t.Fatalf("SetChunkDelay failed: %v", err)
+	}
+
+	payload := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if err := fm.transfer(FirmwareStageFirmware, payload); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	// STX, three chunks (4 + 4 + 2 bytes), ETX, CR
+	expectedSizes := []int{1, 4, 4, 2, 1, 1}
+	sizes := make([]int, len(recorder.writes))
+	for i, w := range recorder.writes {
+		sizes[i] = len(w)
+	}
+	if !slices.Equal(sizes, expectedSizes) {
+		t.Fatalf("Expected write sizes %v, got %v", expectedSizes, sizes)
+	}
+	if recorder.writes[0][0] != 0x02 || recorder.writes[4][0] != 0x03 || string(recorder.writes[5]) != "\r" {
+		t.Errorf("Expected STX/ETX/CR framing, got %v", recorder.writes)
+	}
+	if recorder.drains != 3 {
+		t.Errorf("Expected a drain after each of the 3 chunks, got %d", recorder.drains)
+	}
+}
+
+func TestParseChecksumReply(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected uint32
+		ok       bool
+	}{
+		{"Checksum: 0x1d872b41", 0x1d872b41, true},
+		{"checksum 495397697", 495397697, true},
+		{"Image received, checksum = 0xDEADBEEF", 0xdeadbeef, true},
+		{"checksum error", 0, false},
+		{"BHBL> ", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			value, ok := parseChecksumReply(tt.line)
+			if ok != tt.ok || value != tt.expected {
+				t.Errorf("parseChecksumReply(%q) = (0x%x, %v), expected (0x%x, %v)", tt.line, value, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFirmwareManager_LoadFirmwareChecksum(t *testing.T) {
+	firmware := []byte{0x01, 0x02, 0x03, 0x04}
+
+	tests := []struct {
+		name    string
+		reply   func(checksum uint32) string
+		wantErr bool
+	}{
+		{"matching checksum", func(c uint32) string { return fmt.Sprintf("Checksum: 0x%08x", c) }, false},
+		{"mismatching checksum", func(c uint32) string { return fmt.Sprintf("Checksum: 0x%08x", c+1) }, true},
+		{"non-numeric checksum", func(uint32) string { return "Checksum: error" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brick := TestBrick(t)
+			defer CleanupTestBrick(brick)
+
+			fm := brick.firmwareManager
+			mockPort := brick.GetMockPort()
+			checksum := fm.calculateChecksum(firmware)
+
+			// Reply once the ETX marker closing the payload has been written
+			go func() {
+				for !slices.Contains(mockPort.GetWriteHistory(), "\x03") {
+					time.Sleep(time.Millisecond)
+				}
+				mockPort.QueueReadData(tt.reply(checksum) + "\r\n")
+			}()
+
+			err := fm.loadFirmware(firmware)
+			if tt.wantErr && err == nil {
+				t.Fatal("Expected checksum mismatch error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("loadFirmware failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestFirmwareManager_LoadFirmware_NoChecksumReply(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	fm := brick.firmwareManager
+	fm.checksumTimeout = 10 * time.Millisecond
+
+	if err := fm.loadFirmware([]byte{0x01, 0x02, 0x03, 0x04}); err == nil {
+		t.Fatal("Expected error when the HAT sends no checksum reply")
+	}
+}
+
+func TestFirmwareManager_UpdateFirmwareFrom_NoChecksumReplyStops(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.firmwareManager.checksumTimeout = 10 * time.Millisecond
+
+	if err := brick.UpdateFirmwareFrom([]byte{0x10, 0x20}, []byte{0xaa}, "v"); err == nil {
+		t.Fatal("Expected UpdateFirmwareFrom to fail without a checksum reply")
+	}
+
+	for _, cmd := range brick.GetMockPort().GetWriteHistory() {
+		if strings.HasPrefix(cmd, "signature") || strings.HasPrefix(cmd, "reboot") {
+			t.Errorf("Expected no %q without a checksum reply", cmd)
+		}
+	}
+}
+
+func TestFirmwareManager_UpdateFirmware_ChecksumMismatchStops(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	go func() {
+		for !slices.Contains(mockPort.GetWriteHistory(), "\x03") {
+			time.Sleep(time.Millisecond)
+		}
+		mockPort.QueueReadData("Checksum: 0x00000000\r\n")
+	}()
+
+	if err := brick.firmwareManager.updateFirmware(); err == nil {
+		t.Fatal("Expected updateFirmware to fail on checksum mismatch")
+	}
+
+	for _, cmd := range mockPort.GetWriteHistory() {
+		if strings.HasPrefix(cmd, "signature") || strings.HasPrefix(cmd, "reboot") {
+			t.Errorf("Expected no %q after a checksum mismatch", cmd)
+		}
+	}
+}
+
+func TestBrick_UpdateFirmwareFrom(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	firmware := []byte{0x10, 0x20, 0x30, 0x40, 0x50}
+	signature := []byte{0xaa, 0xbb}
+	replyChecksum(brick, firmware)
+
+	if err := brick.UpdateFirmwareFrom(firmware, signature, "1800000000"); err != nil {
+		t.Fatalf("UpdateFirmwareFrom failed: %v", err)
+	}
+
+	history := brick.GetMockPort().GetWriteHistory()
+	expectedLoad := Load(len(firmware), int(brick.firmwareManager.calculateChecksum(firmware))).CommandString() + "\r"
+	if !slices.Contains(history, expectedLoad) {
+		t.Errorf("Expected %q in write history, got %v", expectedLoad, history)
+	}
+	expectedSignature := SignatureLoad(len(signature)).CommandString() + "\r"
+	if !slices.Contains(history, expectedSignature) {
+		t.Errorf("Expected %q in write history, got %v", expectedSignature, history)
+	}
+	if !slices.Contains(history, string(firmware)) {
+		t.Error("Expected the supplied firmware bytes to be written")
+	}
+}
+
+func TestBrick_UpdateFirmwareFrom_RejectsEmpty(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.UpdateFirmwareFrom(nil, []byte{0x01}, "v"); err == nil {
+		t.Error("Expected error for empty firmware")
+	}
+	if err := brick.UpdateFirmwareFrom([]byte{0x01}, nil, "v"); err == nil {
+		t.Error("Expected error for empty signature")
+	}
+	if history := brick.GetMockPort().GetWriteHistory(); len(history) != 0 {
+		t.Errorf("Expected nothing written for invalid input, got %v", history)
+	}
+}
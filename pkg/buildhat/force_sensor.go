@@ -6,23 +6,49 @@ import (
 
 // ForceSensor creates a force sensor interface for the specified port
 func (b *Brick) ForceSensor(port Port) *ForceSensor {
-	return &ForceSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *ForceSensor {
+		return &ForceSensor{
+			brick: b,
+			port:  port,
+		}
+	})
 }
 
 // ForceSensor provides a Python-like force sensor interface
 type ForceSensor struct {
-	brick *Brick
-	port  Port
+	brick          *Brick
+	port           Port
+	pressThreshold int // pressed when the force exceeds this, in Newtons
+}
+
+// forceRawMode is the FRAW mode, reporting the uncalibrated sensor value
+const forceRawMode = 4
+
+// SetPressThreshold sets the force, in Newtons, that must be exceeded for IsPressed to report
+// a press, so light touches can be ignored. The default of 0 counts any force.
+func (s *ForceSensor) SetPressThreshold(newtons int) error {
+	if newtons < 0 {
+		return fmt.Errorf("press threshold must not be negative")
+	}
+	s.pressThreshold = newtons
+	return nil
 }
 
-// GetForce gets the current force reading in Newtons
+// GetForce gets the current calibrated force reading in Newtons
 func (s *ForceSensor) GetForce() (int, error) {
 	// Python uses combi mode: [(0, 0), (1, 0), (3, 0)]
 	// For simplicity, we'll just use mode 0
-	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(0))); err != nil {
+	return s.readForce(0)
+}
+
+// GetRawForce gets the uncalibrated reading of the force sensor element
+func (s *ForceSensor) GetRawForce() (int, error) {
+	return s.readForce(forceRawMode)
+}
+
+// readForce selects a mode and returns its first value
+func (s *ForceSensor) readForce(mode int) (int, error) {
+	if err := s.brick.writeCommand(Compound(SelectPort(s.port), Select(mode))); err != nil {
 		return 0, err
 	}
 
@@ -51,6 +77,6 @@ func (s *ForceSensor) IsPressed() (bool, error) {
 		return false, err
 	}
 
-	// Consider pressed if force > 0
-	return force > 0, nil
+	// Consider pressed if force exceeds the threshold (0 by default)
+	return force > s.pressThreshold, nil
 }
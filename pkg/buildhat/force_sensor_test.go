@@ -56,3 +56,57 @@ func TestForceSensor_AllPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestForceSensor_SetPressThreshold(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.ForceSensor(PortB)
+
+	// Default: any force is a press
+	mockPort.SimulateSensorResponse("B", 0, "1")
+	if pressed, err := sensor.IsPressed(); err != nil || !pressed {
+		t.Errorf("Expected a force of 1 to be pressed by default, got %v (%v)", pressed, err)
+	}
+
+	if err := sensor.SetPressThreshold(3); err != nil {
+		t.Fatalf("SetPressThreshold failed: %v", err)
+	}
+	for force, want := range map[string]bool{"2": false, "3": false, "4": true} {
+		mockPort.SimulateSensorResponse("B", 0, force)
+		pressed, err := sensor.IsPressed()
+		if err != nil {
+			t.Fatalf("IsPressed failed: %v", err)
+		}
+		if pressed != want {
+			t.Errorf("Force %s with threshold 3: expected pressed=%v, got %v", force, want, pressed)
+		}
+	}
+
+	if err := sensor.SetPressThreshold(-1); err == nil {
+		t.Error("Expected error for a negative threshold")
+	}
+}
+
+func TestForceSensor_GetRawForce(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("C", 4, "612")
+
+	sensor := brick.ForceSensor(PortC)
+	raw, err := sensor.GetRawForce()
+	if err != nil {
+		t.Fatalf("GetRawForce failed: %v", err)
+	}
+	if raw != 612 {
+		t.Errorf("Expected raw force 612, got %d", raw)
+	}
+
+	// Verify EXACT command: "port 2 ; select 4\r"
+	if last := mockPort.GetLastWrite(); last != "port 2 ; select 4\r" {
+		t.Errorf("Expected exact command 'port 2 ; select 4\\r', got: %s", last)
+	}
+}
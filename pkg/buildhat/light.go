@@ -2,20 +2,47 @@ package buildhat
 
 import (
 	"fmt"
+	"math"
+	"time"
 )
 
+// defaultLightBrightness is reported by GetBrightness before any brightness was set
+const defaultLightBrightness = 50
+
 // Light creates a light interface for the specified port
 func (b *Brick) Light(port Port) *Light {
-	return &Light{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *Light {
+		return &Light{
+			brick: b,
+			port:  port,
+			gamma: 1,
+		}
+	})
 }
 
 // Light provides a Python-like light interface
 type Light struct {
-	brick *Brick
-	port  Port
+	brick         *Brick
+	port          Port
+	brightness    int     // last brightness set through this light
+	brightnessSet bool    // whether brightness holds a set value
+	gamma         float64 // exponent applied to brightness setpoints, see SetGamma
+}
+
+// SetGamma sets the gamma curve applied to brightness setpoints, so equal brightness steps
+// look even: the light is driven at (brightness/100)^gamma. A gamma of about 2.2 makes fades
+// look smooth to the eye; the default of 1 drives the light linearly.
+func (l *Light) SetGamma(gamma float64) error {
+	if gamma <= 0 || math.IsInf(gamma, 0) || math.IsNaN(gamma) {
+		return fmt.Errorf("gamma must be a positive number")
+	}
+	l.gamma = gamma
+	return nil
+}
+
+// setpoint converts a brightness (0-100) to the 0.0-1.0 output value, applying the gamma
+func (l *Light) setpoint(brightness int) float64 {
+	return math.Pow(float64(brightness)/100.0, l.gamma)
 }
 
 // SetBrightness sets the brightness of the light (0-100)
@@ -28,9 +55,12 @@ func (l *Light) SetBrightness(brightness int) error {
 		return l.Off()
 	}
 
-	// Convert brightness to 0.0-1.0 range
-	value := float64(brightness) / 100.0
-	return l.brick.writeCommand(Compound(SelectPort(l.port), On(), SetConstantFormatted(value, "%.2f")))
+	value := l.setpoint(brightness)
+	if err := l.brick.writeCommand(Compound(SelectPort(l.port), On(), SetConstantFormatted(value, "%.2f"))); err != nil {
+		return err
+	}
+	l.brightness, l.brightnessSet = brightness, true
+	return nil
 }
 
 // On turns the light on at full brightness
@@ -41,11 +71,54 @@ func (l *Light) On() error {
 // Off turns the light off
 func (l *Light) Off() error {
 	// Using coast to turn off lights completely
-	return l.brick.writeCommand(Compound(SelectPort(l.port), Coast()))
+	if err := l.brick.writeCommand(Compound(SelectPort(l.port), Coast())); err != nil {
+		return err
+	}
+	l.brightness, l.brightnessSet = 0, true
+	return nil
 }
 
-// GetBrightness gets the current brightness reading (not supported on all lights)
-// This returns the stored data value, which may not be available for all light types
+// Blink flashes the light between off and onBrightness (1-100) with the given period in seconds.
+// The HAT generates the square wave itself, lit for half of each period, until another
+// brightness is set. GetBrightness reports onBrightness while it blinks.
+func (l *Light) Blink(onBrightness int, period float64) error {
+	if onBrightness < 1 || onBrightness > 100 {
+		return fmt.Errorf("brightness must be between 1 and 100")
+	}
+	if period <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+
+	value := l.setpoint(onBrightness)
+	if err := l.brick.writeCommand(Compound(SelectPort(l.port), On(), SetSquareWave(0, value, period, 0))); err != nil {
+		return err
+	}
+	l.brightness, l.brightnessSet = onBrightness, true
+	return nil
+}
+
+// Pulse lights the light at brightness (1-100) for duration, after which the HAT turns it off.
+// It returns without waiting for the pulse to end. GetBrightness reports brightness from then
+// on, as it isn't told when the HAT turns the light off.
+func (l *Light) Pulse(brightness int, duration time.Duration) error {
+	if brightness < 1 || brightness > 100 {
+		return fmt.Errorf("brightness must be between 1 and 100")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+
+	value := l.setpoint(brightness)
+	if err := l.brick.writeCommand(Compound(SelectPort(l.port), On(), SetPulse(value, 0.0, duration.Seconds()))); err != nil {
+		return err
+	}
+	l.brightness, l.brightnessSet = brightness, true
+	return nil
+}
+
+// GetBrightness gets the current brightness reading (not supported on all lights).
+// Lights that don't report back return the last brightness set through this Light, the on
+// level for Blink and Pulse, or defaultLightBrightness (50) if none was set yet.
 func (l *Light) GetBrightness() (int, error) {
 	l.brick.mu.RLock()
 	defer l.brick.mu.RUnlock()
@@ -56,6 +129,8 @@ func (l *Light) GetBrightness() (int, error) {
 		}
 	}
 
-	// Default to a middle value if no data available
-	return 50, nil
+	if l.brightnessSet {
+		return l.brightness, nil
+	}
+	return defaultLightBrightness, nil
 }
@@ -1,7 +1,9 @@
 package buildhat
 
 import (
+	"math"
 	"testing"
+	"time"
 )
 
 func TestLight_On(t *testing.T) {
@@ -106,6 +108,42 @@ func TestLight_SetBrightness_Values(t *testing.T) {
 	}
 }
 
+func TestLight_SetGamma(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	light := brick.Light(PortD)
+	mockPort := brick.GetMockPort()
+
+	if err := light.SetGamma(2.2); err != nil {
+		t.Fatalf("SetGamma(2.2) failed: %v", err)
+	}
+	if err := light.SetBrightness(50); err != nil {
+		t.Fatalf("SetBrightness(50) failed: %v", err)
+	}
+	// 0.5^2.2 = 0.2176
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 3 ; on ; set 0.22\r" {
+		t.Errorf("Expected exact command 'port 3 ; on ; set 0.22\\r', got: %s", lastCmd)
+	}
+	if brightness, _ := light.GetBrightness(); brightness != 50 {
+		t.Errorf("Expected GetBrightness to report the requested 50, got %d", brightness)
+	}
+
+	// Full brightness is unaffected by the curve
+	if err := light.SetBrightness(100); err != nil {
+		t.Fatalf("SetBrightness(100) failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 3 ; on ; set 1.00\r" {
+		t.Errorf("Expected exact command 'port 3 ; on ; set 1.00\\r', got: %s", lastCmd)
+	}
+
+	for _, gamma := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		if err := light.SetGamma(gamma); err == nil {
+			t.Errorf("SetGamma(%v) should have failed", gamma)
+		}
+	}
+}
+
 func TestLight_GetBrightness(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -124,6 +162,86 @@ func TestLight_GetBrightness(t *testing.T) {
 	}
 }
 
+func TestLight_GetBrightness_ReturnsLastSet(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	light := brick.Light(PortA)
+
+	if err := light.SetBrightness(30); err != nil {
+		t.Fatalf("SetBrightness(30) failed: %v", err)
+	}
+	brightness, err := light.GetBrightness()
+	if err != nil {
+		t.Fatalf("GetBrightness failed: %v", err)
+	}
+	if brightness != 30 {
+		t.Errorf("Expected brightness 30, got %d", brightness)
+	}
+
+	if err := light.Off(); err != nil {
+		t.Fatalf("Off failed: %v", err)
+	}
+	if brightness, _ := light.GetBrightness(); brightness != 0 {
+		t.Errorf("Expected brightness 0 after Off, got %d", brightness)
+	}
+}
+
+func TestLight_Blink(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	light := brick.Light(PortB)
+	if err := light.Blink(80, 0.5); err != nil {
+		t.Fatalf("Blink failed: %v", err)
+	}
+
+	expected := "port 1 ; on ; set square 0 0.8 0.5 0\r"
+	if lastCmd := brick.GetMockPort().GetLastWrite(); lastCmd != expected {
+		t.Errorf("Expected exact command '%s', got: %s", expected, lastCmd)
+	}
+	if brightness, _ := light.GetBrightness(); brightness != 80 {
+		t.Errorf("Expected brightness 80 while blinking, got %d", brightness)
+	}
+
+	invalid := []struct {
+		brightness int
+		period     float64
+	}{
+		{0, 1}, {101, 1}, {50, 0}, {50, -1},
+	}
+	for _, tc := range invalid {
+		if err := light.Blink(tc.brightness, tc.period); err == nil {
+			t.Errorf("Blink(%d, %g) should have failed", tc.brightness, tc.period)
+		}
+	}
+}
+
+func TestLight_Pulse(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	light := brick.Light(PortC)
+	if err := light.Pulse(40, 250*time.Millisecond); err != nil {
+		t.Fatalf("Pulse failed: %v", err)
+	}
+
+	expected := "port 2 ; on ; set pulse 0.400000 0.0 0.250000 0\r"
+	if lastCmd := brick.GetMockPort().GetLastWrite(); lastCmd != expected {
+		t.Errorf("Expected exact command '%s', got: %s", expected, lastCmd)
+	}
+	if brightness, _ := light.GetBrightness(); brightness != 40 {
+		t.Errorf("Expected the pulse brightness 40, got %d", brightness)
+	}
+
+	if err := light.Pulse(0, time.Second); err == nil {
+		t.Error("Pulse with zero brightness should have failed")
+	}
+	if err := light.Pulse(50, 0); err == nil {
+		t.Error("Pulse with zero duration should have failed")
+	}
+}
+
 func TestLight_AllPorts(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -53,11 +53,13 @@ func (c MatrixColor) String() string {
 
 // Matrix creates a matrix interface for the specified port
 func (b *Brick) Matrix(port Port) *Matrix {
-	return &Matrix{
-		brick:  b,
-		port:   port,
-		pixels: [3][3]Pixel{},
-	}
+	return cachedDevice(b, port, func() *Matrix {
+		return &Matrix{
+			brick:  b,
+			port:   port,
+			pixels: [3][3]Pixel{},
+		}
+	})
 }
 
 // Pixel represents a single LED pixel with color and brightness
@@ -142,6 +144,23 @@ func (m *Matrix) SetColumn(col int, color MatrixColor, brightness int) error {
 	return m.display()
 }
 
+// matrixModeLevel is the matrix's level output mode (LEV O), lighting a number of pixels
+const matrixModeLevel = 0
+
+// SetLevel shows a bar graph lighting level pixels (0-9), like the Python library's level.
+// It uses the matrix's own level mode (mode 0) rather than per-pixel data, and replaces the
+// displayed pixels until the next pixel update.
+func (m *Matrix) SetLevel(level int) error {
+	if level < 0 || level > 9 {
+		return fmt.Errorf("level must be 0-9")
+	}
+	return m.brick.writeCommand(Compound(
+		SelectPort(m.port),
+		Select(matrixModeLevel),
+		Write1(0xc0|matrixModeLevel, byte(level)),
+	))
+}
+
 // Clear turns off all pixels
 func (m *Matrix) Clear() error {
 	return m.SetAll(MatrixBlack, 0)
@@ -266,6 +266,32 @@ func TestMatrix_Clear(t *testing.T) {
 	}
 }
 
+func TestMatrix_SetLevel(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	matrix := brick.Matrix(PortB)
+
+	if err := matrix.SetLevel(5); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	expectedCmd := "port 1 ; select 0 ; write1 c0 5\r"
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != expectedCmd {
+		t.Errorf("Expected exact command '%s', got: %s", expectedCmd, lastCmd)
+	}
+
+	mockPort.ClearWriteHistory()
+	for _, level := range []int{-1, 10} {
+		if err := matrix.SetLevel(level); err == nil {
+			t.Errorf("SetLevel(%d) should have failed", level)
+		}
+	}
+	if count := mockPort.GetWriteCount(); count != 0 {
+		t.Errorf("Expected no commands for invalid levels, got %d", count)
+	}
+}
+
 func TestMatrix_AllPorts(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
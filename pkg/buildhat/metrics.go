@@ -0,0 +1,36 @@
+package buildhat
+
+import "sync/atomic"
+
+// Metrics are counters describing the health of the connection to the HAT
+type Metrics struct {
+	CommandsSent   uint64           `json:"commands_sent"`
+	LinesReceived  uint64           `json:"lines_received"`
+	ParseFailures  uint64           `json:"parse_failures"` // Received lines no parser recognised
+	Timeouts       uint64           `json:"timeouts"`       // Replies and completions not received in time
+	PortReconnects [NumPorts]uint64 `json:"port_reconnects"`
+}
+
+// brickMetrics holds the live counters, updated atomically from the reader and command paths
+type brickMetrics struct {
+	commandsSent   atomic.Uint64
+	linesReceived  atomic.Uint64
+	parseFailures  atomic.Uint64
+	timeouts       atomic.Uint64
+	portReconnects [NumPorts]atomic.Uint64
+}
+
+// Metrics returns the current counters. PortReconnects counts devices detected on a port
+// again after being disconnected, e.g. a flaky cable or a re-plugged device.
+func (b *Brick) Metrics() Metrics {
+	m := Metrics{
+		CommandsSent:  b.metrics.commandsSent.Load(),
+		LinesReceived: b.metrics.linesReceived.Load(),
+		ParseFailures: b.metrics.parseFailures.Load(),
+		Timeouts:      b.metrics.timeouts.Load(),
+	}
+	for i := range m.PortReconnects {
+		m.PortReconnects[i] = b.metrics.portReconnects[i].Load()
+	}
+	return m
+}
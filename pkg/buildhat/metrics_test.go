@@ -0,0 +1,63 @@
+package buildhat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrick_Metrics_CountsCommandsLinesAndTimeouts(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	before := brick.Metrics()
+
+	if err := brick.SendRawCommand("list"); err != nil {
+		t.Fatalf("SendRawCommand failed: %v", err)
+	}
+	brick.parseLine("P0: connected to active ID 30")
+	brick.parseLine("not a message the HAT sends")
+
+	brick.sensorTimeout = 20 * time.Millisecond
+	if _, err := brick.getSensorData(PortC); err == nil {
+		t.Fatal("Expected timeout error")
+	}
+
+	m := brick.Metrics()
+	if got := m.CommandsSent - before.CommandsSent; got != 1 {
+		t.Errorf("Expected 1 command sent, got %d", got)
+	}
+	if got := m.LinesReceived - before.LinesReceived; got != 2 {
+		t.Errorf("Expected 2 lines received, got %d", got)
+	}
+	if got := m.ParseFailures - before.ParseFailures; got != 1 {
+		t.Errorf("Expected 1 parse failure, got %d", got)
+	}
+	if got := m.Timeouts - before.Timeouts; got != 1 {
+		t.Errorf("Expected 1 timeout, got %d", got)
+	}
+}
+
+func TestBrick_Metrics_PortReconnects(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	// The first connection is not a reconnect, nor is an empty port staying empty
+	brick.parseLine("P1: no device detected")
+	brick.parseLine("P1: connected to active ID 30")
+	if got := brick.Metrics().PortReconnects[PortB]; got != 0 {
+		t.Fatalf("Expected no reconnects after first connection, got %d", got)
+	}
+
+	for range 2 {
+		brick.parseLine("P1: disconnected")
+		brick.parseLine("P1: connected to active ID 30")
+	}
+
+	m := brick.Metrics()
+	if m.PortReconnects[PortB] != 2 {
+		t.Errorf("Expected 2 reconnects on port B, got %d", m.PortReconnects[PortB])
+	}
+	if m.PortReconnects[PortA] != 0 {
+		t.Errorf("Expected no reconnects on port A, got %d", m.PortReconnects[PortA])
+	}
+}
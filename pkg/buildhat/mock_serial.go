@@ -3,39 +3,64 @@ package buildhat
 import (
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	"go.bug.st/serial"
 )
 
+// defaultMockReadTimeout is how long Read waits for data until SetReadTimeout is called
+const defaultMockReadTimeout = 5 * time.Millisecond
+
 // MockSerialPort implements a mock serial port for testing
 type MockSerialPort struct {
 	mu           sync.RWMutex
+	dataReady    *sync.Cond // signalled when read data is queued or the port is closed
+	readTimeout  time.Duration
 	readBuffer   []byte
 	writeBuffer  []byte
 	writeHistory []string
 	readHistory  []string
+	rules        []responseRule
+	writeLatency time.Duration
+	readLatency  time.Duration
+	readErr      error // injected error returned by Read once readErrAfter reads succeeded
+	readErrAfter int
 	closed       bool
 	logger       *slog.Logger
 }
 
+// responseRule queues responses whenever a written command matches
+type responseRule struct {
+	match     func(cmd string) bool
+	responses []string
+}
+
 // NewMockSerialPort creates a new mock serial port
 func NewMockSerialPort(logger *slog.Logger) *MockSerialPort {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &MockSerialPort{
+	m := &MockSerialPort{
+		readTimeout:  defaultMockReadTimeout,
 		readBuffer:   make([]byte, 0),
 		writeBuffer:  make([]byte, 0),
 		writeHistory: make([]string, 0),
 		readHistory:  make([]string, 0),
 		logger:       logger,
 	}
+	m.dataReady = sync.NewCond(&m.mu)
+	return m
 }
 
 // Write implements io.Writer
 func (m *MockSerialPort) Write(data []byte) (int, error) {
+	m.mu.RLock()
+	latency := m.writeLatency
+	m.mu.RUnlock()
+	time.Sleep(latency)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -48,36 +73,70 @@ func (m *MockSerialPort) Write(data []byte) (int, error) {
 
 	m.logger.Debug("MockSerialPort.Write", "data", string(data), "hex", fmt.Sprintf("%x", data))
 
+	// Scripted responses
+	cmd := strings.TrimRight(string(data), "\r\n")
+	for _, rule := range m.rules {
+		if rule.match(cmd) {
+			for _, response := range rule.responses {
+				m.queueLocked(response + "\r\n")
+			}
+		}
+	}
+
 	// Auto-respond to motor commands with completion messages
 	go m.autoRespond(string(data))
 
 	return len(data), nil
 }
 
-// Read implements io.Reader
+// Read implements io.Reader. Like a real serial port, it blocks until data is queued or the
+// read timeout expires, in which case it returns 0 bytes with no error.
 func (m *MockSerialPort) Read(data []byte) (int, error) {
+	m.mu.RLock()
+	latency := m.readLatency
+	m.mu.RUnlock()
+	time.Sleep(latency)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.readErr != nil && m.readErrAfter == 0 {
+		err := m.readErr
+		m.readErr = nil
+		return 0, err
+	}
+
+	if len(m.readBuffer) == 0 && !m.closed && m.readTimeout != 0 {
+		var deadline time.Time
+		if m.readTimeout > 0 {
+			deadline = time.Now().Add(m.readTimeout)
+			timer := time.AfterFunc(m.readTimeout, func() {
+				m.mu.Lock()
+				m.dataReady.Broadcast()
+				m.mu.Unlock()
+			})
+			defer timer.Stop()
+		}
+
+		for len(m.readBuffer) == 0 && !m.closed && (deadline.IsZero() || time.Now().Before(deadline)) {
+			m.dataReady.Wait()
+		}
+	}
+
 	if m.closed {
 		return 0, fmt.Errorf("port is closed")
 	}
 
 	if len(m.readBuffer) == 0 {
-		// Release lock and wait a bit for data to arrive
-		m.mu.Unlock()
-		time.Sleep(5 * time.Millisecond) // Increased from 1ms for better CI reliability
-		m.mu.Lock()
-
-		// Check again after waiting
-		if len(m.readBuffer) == 0 {
-			// Still no data, return 0 bytes with no error
-			return 0, nil
-		}
+		// Timed out, return 0 bytes with no error
+		return 0, nil
 	}
 
 	n := copy(data, m.readBuffer)
 	m.readBuffer = m.readBuffer[n:]
+	if m.readErr != nil {
+		m.readErrAfter--
+	}
 
 	m.logger.Debug("MockSerialPort.Read", "data", string(data[:n]), "hex", fmt.Sprintf("%x", data[:n]))
 	return n, nil
@@ -89,12 +148,18 @@ func (m *MockSerialPort) Close() error {
 	defer m.mu.Unlock()
 
 	m.closed = true
+	m.dataReady.Broadcast()
 	m.logger.Debug("MockSerialPort.Close")
 	return nil
 }
 
-// SetReadTimeout sets a mock read timeout (not implemented in real serial)
-func (m *MockSerialPort) SetReadTimeout(_ time.Duration) error {
+// SetReadTimeout sets how long Read blocks waiting for data.
+// A negative timeout blocks until data arrives or the port is closed; zero never blocks.
+func (m *MockSerialPort) SetReadTimeout(timeout time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readTimeout = timeout
 	return nil
 }
 
@@ -103,6 +168,32 @@ func (m *MockSerialPort) SetWriteTimeout(_ time.Duration) error {
 	return nil
 }
 
+// SetWriteLatency delays every Write by d to simulate a slow device
+func (m *MockSerialPort) SetWriteLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.writeLatency = d
+}
+
+// SetReadLatency delays every Read by d to simulate a slow device
+func (m *MockSerialPort) SetReadLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readLatency = d
+}
+
+// InjectReadError makes Read fail once with err after afterN further reads have returned data.
+// Reads resume normally after the error has been returned.
+func (m *MockSerialPort) InjectReadError(err error, afterN int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readErr = err
+	m.readErrAfter = max(afterN, 0)
+}
+
 // Break sends a break signal (not implemented in mock)
 func (m *MockSerialPort) Break(_ time.Duration) error {
 	return nil
@@ -152,6 +243,10 @@ func (m *MockSerialPort) Reset() {
 	m.writeBuffer = make([]byte, 0)
 	m.writeHistory = make([]string, 0)
 	m.readHistory = make([]string, 0)
+	m.rules = nil
+	m.writeLatency = 0
+	m.readLatency = 0
+	m.readErr = nil
 	m.closed = false
 }
 
@@ -160,11 +255,26 @@ func (m *MockSerialPort) QueueReadData(data string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.queueLocked(data)
+}
+
+// queueLocked queues read data; the caller must hold m.mu
+func (m *MockSerialPort) queueLocked(data string) {
 	m.readBuffer = append(m.readBuffer, []byte(data)...)
 	m.readHistory = append(m.readHistory, data)
+	m.dataReady.Broadcast()
 	m.logger.Debug("MockSerialPort.QueueReadData", "data", data)
 }
 
+// RespondTo queues responses, one line each, every time a command accepted by match is written.
+// match receives the command without its trailing line terminator.
+func (m *MockSerialPort) RespondTo(match func(cmd string) bool, responses ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rules = append(m.rules, responseRule{match: match, responses: responses})
+}
+
 // GetWriteHistory returns all data written to the port
 func (m *MockSerialPort) GetWriteHistory() []string {
 	m.mu.RLock()
@@ -173,6 +283,23 @@ func (m *MockSerialPort) GetWriteHistory() []string {
 	return append([]string{}, m.writeHistory...)
 }
 
+// GetCommandHistory returns the written commands as normalized tokens: line endings are
+// stripped and compound commands split on ";", so "port 0 ; coast\r" gives "port 0", "coast"
+func (m *MockSerialPort) GetCommandHistory() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	commands := make([]string, 0, len(m.writeHistory))
+	for _, write := range m.writeHistory {
+		for _, part := range strings.Split(strings.TrimRight(write, "\r\n"), ";") {
+			if part = strings.TrimSpace(part); part != "" {
+				commands = append(commands, part)
+			}
+		}
+	}
+	return commands
+}
+
 // ClearWriteHistory clears the write history
 func (m *MockSerialPort) ClearWriteHistory() {
 	m.mu.Lock()
@@ -227,6 +354,27 @@ func (m *MockSerialPort) SimulateFirmwareNormal() {
 	m.QueueReadData("1737564117 2025-01-22T16:41:57+00:00\r\n")
 }
 
+// SimulateHelpResponse simulates the command synopsis printed in reply to "help"
+func (m *MockSerialPort) SimulateHelpResponse() {
+	m.QueueReadData("help\r\n")
+	for _, line := range mockHelpLines {
+		m.QueueReadData(line + "\r\n")
+	}
+	m.QueueReadData("> \r\n")
+}
+
+// mockHelpLines is an excerpt of the firmware help text
+var mockHelpLines = []string{
+	"help, ?             : display this text",
+	"port <port>         : select a port (default 0)",
+	"vin                 : print the input voltage",
+	"list                : list connected devices",
+	"select <mode>       : select a mode on the current port",
+	"selrate <rate>      : set the interval between readings",
+	"echo <0|1>          : enable or disable echo",
+	"version             : print version string",
+}
+
 // SimulateDeviceList simulates device list responses
 func (m *MockSerialPort) SimulateDeviceList() {
 	m.QueueReadData("list\r\n")
@@ -248,57 +396,98 @@ func (m *MockSerialPort) SimulateMotorResponse(port string, speed int) {
 
 // SimulateSensorResponse simulates sensor reading responses
 func (m *MockSerialPort) SimulateSensorResponse(port string, mode int, value string) {
-	// Convert port letter to number (A=0, B=1, C=2, D=3)
-	portNum := 0
-	if port != "" && port[0] >= 'A' && port[0] <= 'D' {
-		portNum = int(port[0] - 'A')
-	} else if port != "" && port[0] >= '0' && port[0] <= '3' {
-		portNum = int(port[0] - '0')
-	}
-
 	// Queue the sensor data in the format expected by parseLine
 	// Format: P<port>M<mode>: <data> (matches firmware output format)
-	m.QueueReadData(fmt.Sprintf("P%dM%d: %s\r\n", portNum, mode, value))
+	m.QueueReadData(fmt.Sprintf("P%dM%d: %s\r\n", mockPortNumber(port), mode, value))
+}
+
+// SimulateMotorStream streams motor combi samples (speed, position, absolute position) for a
+// port, one every interval, like a motor in combi mode does. Samples are sent in order and
+// the last one repeats until the returned function is called or the port is closed.
+func (m *MockSerialPort) SimulateMotorStream(port string, samples [][3]int, interval time.Duration) func() {
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() { once.Do(func() { close(done) }) }
+	if len(samples) == 0 {
+		return stop
+	}
+
+	portNum := mockPortNumber(port)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			m.mu.RLock()
+			closed := m.closed
+			m.mu.RUnlock()
+			if closed {
+				return
+			}
+
+			s := samples[min(i, len(samples)-1)]
+			m.QueueReadData(fmt.Sprintf("P%dC0: %d %d %d\r\n", portNum, s[0], s[1], s[2]))
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return stop
 }
 
-// autoRespond automatically responds to certain commands
+// mockPortNumber converts a port letter (A-D) or digit (0-3) to its number, defaulting to 0
+func mockPortNumber(port string) int {
+	if p, err := ParsePort(port); err == nil {
+		return p.Int()
+	}
+	return 0
+}
+
+// autoRespond automatically responds to certain commands. A compound command may address
+// several ports ("port 0 ; set ramp ... ; port 1 ; set ramp ..."), each getting its reply.
 func (m *MockSerialPort) autoRespond(command string) {
 	// Check if it's a ramp or pulse command first
-	hasRamp := containsIgnoreCase(command, "set ramp")
-	hasPulse := containsIgnoreCase(command, "set pulse")
-
-	if !hasRamp && !hasPulse {
+	if !containsIgnoreCase(command, "set ramp") && !containsIgnoreCase(command, "set pulse") {
 		return // Not a command we need to respond to
 	}
 
-	// Parse the command to extract port number
-	// Look for "port <n>" pattern - use strings.Fields for better parsing
-	var portNum int
-	foundPort := false
-
-	// Try scanning from the beginning
-	if n, err := fmt.Sscanf(command, "port %d", &portNum); err == nil && n == 1 {
-		foundPort = true
+	// Collect a reply for every ramp or pulse, on the port selected before it
+	var replies []string
+	portNum := -1
+	for _, part := range strings.Split(strings.TrimRight(command, "\r\n"), ";") {
+		part = strings.TrimSpace(part)
+		var n int
+		if _, err := fmt.Sscanf(part, "port %d", &n); err == nil {
+			portNum = n
+			continue
+		}
+		if portNum < 0 {
+			continue
+		}
+		if containsIgnoreCase(part, "set ramp") {
+			// In real hardware, the HAT sends "ramp done" when movement completes
+			replies = append(replies, fmt.Sprintf("P%d: ramp done\r\n", portNum))
+		} else if containsIgnoreCase(part, "set pulse") {
+			// In real hardware, the HAT sends "pulse done" when time completes
+			replies = append(replies, fmt.Sprintf("P%d: pulse done\r\n", portNum))
+		}
 	}
 
-	if !foundPort {
+	if len(replies) == 0 {
 		m.logger.Debug("AutoRespond: could not parse port number", "command", command)
 		return
 	}
 
-	// Small delay to simulate processing
+	// Small delay to simulate processing, kept short to make tests fast
 	time.Sleep(50 * time.Millisecond)
 
-	if hasRamp {
-		// For testing, use a small delay to make tests fast
-		// In real hardware, the HAT sends "ramp done" when movement completes
-		m.QueueReadData(fmt.Sprintf("P%d: ramp done\r\n", portNum))
-		m.logger.Debug("AutoRespond: ramp done", "port", portNum)
-	} else if hasPulse {
-		// For testing, use a small delay to make tests fast
-		// In real hardware, the HAT sends "pulse done" when time completes
-		m.QueueReadData(fmt.Sprintf("P%d: pulse done\r\n", portNum))
-		m.logger.Debug("AutoRespond: pulse done", "port", portNum)
+	for _, reply := range replies {
+		m.QueueReadData(reply)
+		m.logger.Debug("AutoRespond", "reply", strings.TrimSpace(reply))
 	}
 }
 
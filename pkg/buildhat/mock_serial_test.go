@@ -1,8 +1,10 @@
 package buildhat
 
 import (
+	"errors"
 	"io"
 	"log/slog"
+	"slices"
 	"testing"
 	"time"
 )
@@ -81,6 +83,82 @@ func TestMockSerialPort_SetReadTimeout(t *testing.T) {
 	}
 }
 
+func TestMockSerialPort_Read_BlocksUntilData(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	if err := mockPort.SetReadTimeout(2 * time.Second); err != nil {
+		t.Fatalf("SetReadTimeout failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mockPort.QueueReadData("late data")
+	}()
+
+	start := time.Now()
+	buffer := make([]byte, 16)
+	n, err := mockPort.Read(buffer)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buffer[:n]) != "late data" {
+		t.Errorf("Expected %q, got %q", "late data", string(buffer[:n]))
+	}
+	if elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected Read to wake when data was queued, took %v", elapsed)
+	}
+}
+
+func TestMockSerialPort_Read_Timeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	if err := mockPort.SetReadTimeout(50 * time.Millisecond); err != nil {
+		t.Fatalf("SetReadTimeout failed: %v", err)
+	}
+
+	start := time.Now()
+	n, err := mockPort.Read(make([]byte, 16))
+	elapsed := time.Since(start)
+	if err != nil || n != 0 {
+		t.Errorf("Expected (0, nil) on timeout, got (%d, %v)", n, err)
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Expected Read to block for the timeout, returned after %v", elapsed)
+	}
+}
+
+func TestMockSerialPort_Read_UnblockedByClose(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+
+	if err := mockPort.SetReadTimeout(-1); err != nil {
+		t.Fatalf("SetReadTimeout failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		mockPort.Close()
+	}()
+
+	if _, err := mockPort.Read(make([]byte, 16)); err == nil {
+		t.Error("Expected error once the port is closed")
+	}
+}
+
 func TestMockSerialPort_SetWriteTimeout(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
 		Level: slog.LevelError,
@@ -425,6 +503,32 @@ func TestMockSerialPort_SimulateFirmwareNormal(t *testing.T) {
 	}
 }
 
+func TestMockSerialPort_SimulateHelpResponse(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	// Simulate help output
+	mockPort.SimulateHelpResponse()
+
+	// Echo, synopsis lines, then the prompt
+	readHistory := mockPort.GetReadHistory()
+	if len(readHistory) != len(mockHelpLines)+2 {
+		t.Fatalf("Expected %d read history entries, got %d", len(mockHelpLines)+2, len(readHistory))
+	}
+
+	if readHistory[0] != "help\r\n" {
+		t.Errorf("Expected first read to be 'help\\r\\n', got '%s'", readHistory[0])
+	}
+
+	if last := readHistory[len(readHistory)-1]; last != "> \r\n" {
+		t.Errorf("Expected last read to be the prompt, got '%s'", last)
+	}
+}
+
 func TestMockSerialPort_SimulateDeviceList(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
 		Level: slog.LevelError,
@@ -456,3 +560,157 @@ func TestMockSerialPort_SimulateDeviceList(t *testing.T) {
 		}
 	}
 }
+
+func TestMockSerialPort_RespondTo(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	mockPort.RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+
+	// Non-matching writes queue nothing
+	if _, err := mockPort.Write([]byte("version\r")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if history := mockPort.GetReadHistory(); len(history) != 0 {
+		t.Errorf("Expected no response to a non-matching command, got %v", history)
+	}
+
+	if _, err := mockPort.Write([]byte("vin\r")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	history := mockPort.GetReadHistory()
+	if len(history) != 1 || history[0] != "8.2 V\r\n" {
+		t.Errorf("Expected scripted response, got %v", history)
+	}
+}
+
+func TestMockSerialPort_RespondTo_VoltageRoundTrip(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.GetMockPort().RespondTo(func(cmd string) bool { return cmd == "vin" }, "8.2 V")
+
+	voltage, err := brick.GetVoltage()
+	if err != nil {
+		t.Fatalf("GetVoltage failed: %v", err)
+	}
+	if voltage != 8.2 {
+		t.Errorf("Expected voltage 8.2, got %f", voltage)
+	}
+}
+
+func TestMockSerialPort_InjectReadError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	injected := errors.New("line noise")
+	mockPort.InjectReadError(injected, 1)
+	mockPort.QueueReadData("a")
+
+	buffer := make([]byte, 1)
+	if n, err := mockPort.Read(buffer); err != nil || n != 1 {
+		t.Fatalf("Expected first read to succeed, got (%d, %v)", n, err)
+	}
+	if _, err := mockPort.Read(buffer); !errors.Is(err, injected) {
+		t.Fatalf("Expected injected error, got %v", err)
+	}
+
+	mockPort.QueueReadData("b")
+	if n, err := mockPort.Read(buffer); err != nil || n != 1 || buffer[0] != 'b' {
+		t.Errorf("Expected reads to resume after the error, got (%d, %v)", n, err)
+	}
+}
+
+func TestMockSerialPort_Latency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockPort := NewMockSerialPort(logger)
+	defer mockPort.Close()
+
+	mockPort.SetWriteLatency(30 * time.Millisecond)
+	mockPort.SetReadLatency(30 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := mockPort.Write([]byte("vin\r")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected write latency, took %v", elapsed)
+	}
+
+	mockPort.QueueReadData("8.2 V\r\n")
+	start = time.Now()
+	if _, err := mockPort.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected read latency, took %v", elapsed)
+	}
+}
+
+func TestMockSerialPort_SimulateMotorStream(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	readings, unsubscribe := brick.Subscribe(PortB)
+	defer unsubscribe()
+
+	stop := brick.GetMockPort().SimulateMotorStream("B", [][3]int{{10, 100, 5}, {20, 200, 6}}, 10*time.Millisecond)
+
+	// Samples arrive in order, then the last one repeats
+	expected := [][]any{{10, 100, 5}, {20, 200, 6}, {20, 200, 6}}
+	for i, want := range expected {
+		select {
+		case got := <-readings:
+			if len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+				t.Errorf("Sample %d: expected %v, got %v", i, want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for sample %d", i)
+		}
+	}
+
+	stop()
+	time.Sleep(30 * time.Millisecond)
+	for len(readings) > 0 {
+		<-readings
+	}
+	select {
+	case got := <-readings:
+		t.Errorf("Expected no samples after stop, got %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMockSerialPort_GetCommandHistory(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	if err := brick.writeCommand(Compound(SelectPort(PortA), Select(0), SelRate(10), Coast())); err != nil {
+		t.Fatalf("writeCommand failed: %v", err)
+	}
+	if err := brick.writeCommand(List()); err != nil {
+		t.Fatalf("writeCommand failed: %v", err)
+	}
+
+	expected := []string{"port 0", "select 0", "selrate 10", "coast", "list"}
+	if got := mockPort.GetCommandHistory(); !slices.Equal(got, expected) {
+		t.Errorf("Expected commands %q, got %q", expected, got)
+	}
+
+	mockPort.ClearWriteHistory()
+	if got := mockPort.GetCommandHistory(); len(got) != 0 {
+		t.Errorf("Expected no commands after clearing, got %q", got)
+	}
+}
@@ -0,0 +1,153 @@
+package buildhat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ModeDetails describes one mode of a device, as reported in the "list" output
+type ModeDetails struct {
+	Mode     int     // Mode number
+	Name     string  // Mode name, e.g. "POWER"
+	Unit     string  // SI unit, e.g. "PCT"; empty if none
+	Values   int     // Number of values per reading
+	Type     int     // Value type: 0 = 8-bit, 1 = 16-bit, 2 = 32-bit integer, 3 = float
+	Chars    int     // Suggested display width
+	Decimals int     // Suggested decimal places
+	RawMin   float64 // Raw value range
+	RawMax   float64
+	PctMin   float64 // Percentage range
+	PctMax   float64
+	SIMin    float64 // Range in SI units
+	SIMax    float64
+}
+
+// GetModeDetails returns the modes the device on a port reported during the last "list" scan.
+// Only active devices describe their modes; the result is empty for passive ones.
+func (b *Brick) GetModeDetails(port Port) ([]ModeDetails, error) {
+	if !port.IsValid() {
+		return nil, fmt.Errorf("invalid port: %d", port)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]ModeDetails(nil), b.connections[port.Int()].modes...), nil
+}
+
+// beginModeDescription starts collecting mode details for a newly listed device.
+// The caller must hold b.mu.
+func (b *Brick) beginModeDescription(portID int) {
+	b.describing = portID
+	b.connections[portID].modes = nil
+}
+
+// endModeDescription forgets the mode details of a port with no device. Its status line
+// also ends the description of the previously listed port. The caller must hold b.mu.
+func (b *Brick) endModeDescription(portID int) {
+	b.describing = -1
+	b.connections[portID].modes = nil
+}
+
+// tryParseModeDescription attempts to parse the mode lines following a device in the "list" output.
+// Examples:
+//
+//	"M0 POWER SI = PCT"
+//	"format count=1 type=0 chars=4 dp=0"
+//	"RAW: 00000000 42C80000 PCT: 00000000 42C80000 SI: 00000000 42C80000"
+func (b *Brick) tryParseModeDescription(line string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.describing < 0 {
+		return false
+	}
+	conn := b.connections[b.describing]
+
+	if mode, ok := parseModeHeader(line); ok {
+		conn.modes = append(conn.modes, mode)
+		return true
+	}
+
+	if len(conn.modes) == 0 {
+		return false
+	}
+	current := &conn.modes[len(conn.modes)-1]
+
+	if rest, ok := strings.CutPrefix(line, "format "); ok {
+		for _, field := range strings.Fields(rest) {
+			key, value, _ := strings.Cut(field, "=")
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "count":
+				current.Values = n
+			case "type":
+				current.Type = n
+			case "chars":
+				current.Chars = n
+			case "dp":
+				current.Decimals = n
+			}
+		}
+		return true
+	}
+
+	if strings.HasPrefix(line, "RAW:") {
+		fields := strings.Fields(line)
+		for i := 0; i+2 < len(fields); i += 3 {
+			minVal, minOK := parseModeLimit(fields[i+1])
+			maxVal, maxOK := parseModeLimit(fields[i+2])
+			if !minOK || !maxOK {
+				continue
+			}
+			switch fields[i] {
+			case "RAW:":
+				current.RawMin, current.RawMax = minVal, maxVal
+			case "PCT:":
+				current.PctMin, current.PctMax = minVal, maxVal
+			case "SI:":
+				current.SIMin, current.SIMax = minVal, maxVal
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// parseModeHeader parses a mode header such as "M1 SPEED SI = PCT"
+func parseModeHeader(line string) (ModeDetails, bool) {
+	if len(line) < 2 || line[0] != 'M' || line[1] < '0' || line[1] > '9' {
+		return ModeDetails{}, false
+	}
+
+	header, unit, ok := strings.Cut(line, " SI =")
+	if !ok {
+		return ModeDetails{}, false
+	}
+
+	fields := strings.Fields(header)
+	mode, err := strconv.Atoi(fields[0][1:])
+	if err != nil {
+		return ModeDetails{}, false
+	}
+
+	details := ModeDetails{Mode: mode, Unit: strings.TrimSpace(unit)}
+	if len(fields) > 1 {
+		details.Name = strings.Join(fields[1:], " ")
+	}
+	return details, true
+}
+
+// parseModeLimit parses a range limit, printed by the firmware as the hex bits of a float32
+func parseModeLimit(s string) (float64, bool) {
+	bits, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return float64(math.Float32frombits(uint32(bits))), true
+}
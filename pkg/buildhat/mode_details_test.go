@@ -0,0 +1,123 @@
+package buildhat
+
+import (
+	"strings"
+	"testing"
+)
+
+// listDump is a "list" response, as received before the reader trims each line, for a motor on port A and nothing on port B
+var listDump = []string{
+	"P0: connected to active ID 30",
+	"type 30",
+	"nmodes =5",
+	"nview  =3",
+	"baud   =115200",
+	"hwver  =00000004",
+	"swver  =10000000",
+	" M0 POWER SI = PCT",
+	"    format count=1 type=0 chars=4 dp=0",
+	"    RAW: C2C80000 42C80000    PCT: C2C80000 42C80000    SI: C2C80000 42C80000",
+	" M1 SPEED SI = PCT",
+	"    format count=1 type=0 chars=4 dp=0",
+	"    RAW: C2C80000 42C80000    PCT: C2C80000 42C80000    SI: C2C80000 42C80000",
+	" M2 POS SI = DEG",
+	"    format count=1 type=2 chars=11 dp=0",
+	"    RAW: C3B40000 43B40000    PCT: C2C80000 42C80000    SI: C3B40000 43B40000",
+	" M3 APOS SI = DEG",
+	"    format count=1 type=1 chars=3 dp=0",
+	"    RAW: C3340000 43340000    PCT: C3480000 43480000    SI: C3340000 43340000",
+	" M4 CALIB SI = ",
+	"    format count=2 type=1 chars=5 dp=0",
+	"    RAW: 00000000 45800000    PCT: 00000000 42C80000    SI: 00000000 45800000",
+	" C0: M1+M2+M3",
+	"     speed PID: 00000bb8 00000064 00002328 00000438",
+	"     position PID: 00002ee0 000003e8 00013880 00000000",
+	"P1: no device detected",
+}
+
+func TestBrick_GetModeDetails(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	for _, line := range listDump {
+		brick.parseLine(strings.TrimSpace(line))
+	}
+
+	modes, err := brick.GetModeDetails(PortA)
+	if err != nil {
+		t.Fatalf("GetModeDetails failed: %v", err)
+	}
+	if len(modes) != 5 {
+		t.Fatalf("Expected 5 modes, got %d: %+v", len(modes), modes)
+	}
+
+	expected := []ModeDetails{
+		{Mode: 0, Name: "POWER", Unit: "PCT", Values: 1, Type: 0, Chars: 4, RawMin: -100, RawMax: 100, PctMin: -100, PctMax: 100, SIMin: -100, SIMax: 100},
+		{Mode: 1, Name: "SPEED", Unit: "PCT", Values: 1, Type: 0, Chars: 4, RawMin: -100, RawMax: 100, PctMin: -100, PctMax: 100, SIMin: -100, SIMax: 100},
+		{Mode: 2, Name: "POS", Unit: "DEG", Values: 1, Type: 2, Chars: 11, RawMin: -360, RawMax: 360, PctMin: -100, PctMax: 100, SIMin: -360, SIMax: 360},
+		{Mode: 3, Name: "APOS", Unit: "DEG", Values: 1, Type: 1, Chars: 3, RawMin: -180, RawMax: 180, PctMin: -200, PctMax: 200, SIMin: -180, SIMax: 180},
+		{Mode: 4, Name: "CALIB", Unit: "", Values: 2, Type: 1, Chars: 5, RawMin: 0, RawMax: 4096, PctMin: 0, PctMax: 100, SIMin: 0, SIMax: 4096},
+	}
+	for i, mode := range expected {
+		if modes[i] != mode {
+			t.Errorf("Mode %d: expected %+v, got %+v", i, mode, modes[i])
+		}
+	}
+
+	// Port B has no device, so no modes
+	if modes, _ := brick.GetModeDetails(PortB); len(modes) != 0 {
+		t.Errorf("Expected no modes for an empty port, got %+v", modes)
+	}
+
+	// The description ended, so later mode-like lines are not attributed to port A
+	brick.parseLine("M5 EXTRA SI = PCT")
+	if modes, _ := brick.GetModeDetails(PortA); len(modes) != 5 {
+		t.Errorf("Expected mode lines after the list to be ignored, got %d modes", len(modes))
+	}
+}
+
+func TestBrick_GetModeDetails_ClearedOnDisconnect(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	for _, line := range listDump {
+		brick.parseLine(strings.TrimSpace(line))
+	}
+	brick.parseLine("P0: disconnected")
+
+	modes, err := brick.GetModeDetails(PortA)
+	if err != nil {
+		t.Fatalf("GetModeDetails failed: %v", err)
+	}
+	if len(modes) != 0 {
+		t.Errorf("Expected modes to be cleared on disconnect, got %+v", modes)
+	}
+
+	if _, err := brick.GetModeDetails(Port(9)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestParseModeHeader(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected ModeDetails
+		ok       bool
+	}{
+		{"M0 POWER SI = PCT", ModeDetails{Mode: 0, Name: "POWER", Unit: "PCT"}, true},
+		{"M12 LONG NAME SI = MM", ModeDetails{Mode: 12, Name: "LONG NAME", Unit: "MM"}, true},
+		{"M4 CALIB SI =", ModeDetails{Mode: 4, Name: "CALIB"}, true},
+		{"MX POWER SI = PCT", ModeDetails{}, false},
+		{"M0 POWER", ModeDetails{}, false},
+		{"format count=1", ModeDetails{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			mode, ok := parseModeHeader(tt.line)
+			if ok != tt.ok || mode != tt.expected {
+				t.Errorf("parseModeHeader(%q) = (%+v, %v), expected (%+v, %v)", tt.line, mode, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
@@ -6,10 +6,12 @@ import (
 
 // MotionSensor creates a motion sensor interface for the specified port
 func (b *Brick) MotionSensor(port Port) *MotionSensor {
-	return &MotionSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *MotionSensor {
+		return &MotionSensor{
+			brick: b,
+			port:  port,
+		}
+	})
 }
 
 // MotionSensor provides a Python-like motion sensor interface (WeDo sensor)
@@ -1,7 +1,13 @@
 package buildhat
 
 import (
+	"context"
+	"encoding/csv"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +21,22 @@ const (
 	MotorRunModeSeconds
 )
 
+// String returns the name of the run mode
+func (r MotorRunMode) String() string {
+	switch r {
+	case MotorRunModeNone:
+		return "None"
+	case MotorRunModeFree:
+		return "Free"
+	case MotorRunModeDegrees:
+		return "Degrees"
+	case MotorRunModeSeconds:
+		return "Seconds"
+	default:
+		return fmt.Sprintf("MotorRunMode(%d)", int(r))
+	}
+}
+
 // MotorDirection represents the direction for position-based movements
 type MotorDirection int
 
@@ -24,42 +46,103 @@ const (
 	DirectionAnticlockwise
 )
 
+// defaultReleaseDelay is how long a motor settles after a move before coasting
+const defaultReleaseDelay = 200 * time.Millisecond
+
+// defaultPollInterval is how often RunUntil checks its stop condition
+const defaultPollInterval = 50 * time.Millisecond
+
+// Defaults for re-reading short combi samples, see SetPartialDataRetries
+const (
+	defaultPartialDataRetries = 3
+	defaultPartialDataTimeout = 500 * time.Millisecond
+)
+
+// PIDGains are the tuning constants of a motor controller
+type PIDGains struct {
+	Kp, Ki, Kd float64
+	Windup     float64 // Limit on the integral term
+	Bias       float64 // Deadzone compensation added to the output
+}
+
+// Default controller gains
+var (
+	defaultPositionGains = PIDGains{Kp: 5, Ki: 0, Kd: 0.1, Windup: 3, Bias: 0.01}
+	defaultSpeedGains    = PIDGains{Kp: 0.003, Ki: 0.01, Kd: 0, Windup: 100, Bias: 0.01}
+	defaultRPMGains      = PIDGains{Kp: 0, Ki: 2.5, Kd: 0, Windup: 0.4, Bias: 0.01}
+)
+
 // Motor creates a motor interface for the specified port
 func (b *Brick) Motor(port Port) *Motor {
+	return cachedDevice(b, port, func() *Motor { return b.newMotor(port) })
+}
+
+// newMotor creates and initializes a Motor interface
+func (b *Brick) newMotor(port Port) *Motor {
 	motor := &Motor{
-		brick:        b,
-		port:         port,
-		defaultSpeed: 20,
-		currentSpeed: 0,
-		runMode:      MotorRunModeNone,
-		release:      true,
-		rpm:          false,
+		brick:         b,
+		port:          port,
+		defaultSpeed:  20,
+		currentSpeed:  0,
+		runMode:       MotorRunModeNone,
+		release:       true,
+		releaseDelay:  defaultReleaseDelay,
+		pollInterval:  defaultPollInterval,
+		dataRetries:   defaultPartialDataRetries,
+		dataTimeout:   defaultPartialDataTimeout,
+		accel:         1,
+		decel:         1,
+		positionGains: defaultPositionGains,
+		speedGains:    defaultSpeedGains,
+		rpmGains:      defaultRPMGains,
+		rpm:           false,
 	}
 
 	// Initialize motor with default settings
-	// Set combi mode: mode 1 (speed), mode 2 (position), mode 3 (absolute position)
-	_ = b.writeCommand(Compound(
+	// Set combi mode: mode 1 (speed), mode 2 (position), mode 3 (absolute position),
+	// in the same write as the select, so no reading arrives before it applies
+	if err := b.writeCommand(Compound(
 		SelectPort(port),
 		Combi(0, NewModeDataset(1, 0), NewModeDataset(2, 0), NewModeDataset(3, 0)),
 		Select(0),
-		SelRate(10),
-	))
+		SelRate(combiSelectRate),
+	)); err == nil {
+		b.markCombi(port, 0)
+	}
 
 	_ = motor.SetPowerLimit(0.7)
 	_ = motor.SetPWMParams(0.65, 0.01)
+	_ = motor.SetBias(0.3)
 
 	return motor
 }
 
 // Motor provides a Python-like motor interface
 type Motor struct {
-	brick        *Brick
-	port         Port
-	defaultSpeed int
-	currentSpeed int
-	runMode      MotorRunMode
-	release      bool
-	rpm          bool
+	brick         *Brick
+	port          Port
+	defaultSpeed  int
+	currentSpeed  int
+	runMode       MotorRunMode
+	stopped       chan struct{} // closed when runMode returns to None, nil while idle
+	release       bool
+	releaseDelay  time.Duration
+	pollInterval  time.Duration
+	dataRetries   int           // extra reads when a combi sample is short
+	dataTimeout   time.Duration // bounds the extra reads
+	accel         float64       // position kp multiplier, see SetRampProfile
+	decel         float64       // position kd multiplier, see SetRampProfile
+	positionGains PIDGains
+	speedGains    PIDGains // speed controller gains in percent units
+	rpmGains      PIDGains // speed controller gains in RPM units
+	rpm           bool
+	powerLimit    float64
+	bias          float64
+	pwmThresh     float64
+	minPWM        float64
+
+	// positionOffset is added to the HAT's position, see SetPositionTo
+	positionOffset atomic.Int64
 }
 
 // SetDefaultSpeed sets the default speed of the motor (-100 to 100)
@@ -86,22 +169,125 @@ func (m *Motor) processSpeed(speed int) float64 {
 
 // RunForRotations runs the motor for N rotations
 func (m *Motor) RunForRotations(rotations float64, speed int) error {
-	return m.RunForDegrees(int(rotations*360), speed)
+	return m.RunForDegrees(rotationsToDegrees(rotations), speed)
+}
+
+// rotationsToDegrees converts rotations to the nearest whole degree, so that float error
+// such as 2.999*360 = 1079.64 doesn't lose a degree to truncation
+func rotationsToDegrees(rotations float64) int {
+	return int(math.Round(rotations * 360))
 }
 
 // RunForDegrees runs the motor for the specified number of degrees
 func (m *Motor) RunForDegrees(degrees, speed int) error {
+	return m.RunForDegreesContext(context.Background(), degrees, speed)
+}
+
+// RunForDegreesContext is RunForDegrees that can be cancelled. On cancellation the motor
+// coasts and ctx.Err() is returned.
+func (m *Motor) RunForDegreesContext(ctx context.Context, degrees, speed int) error {
+	ramp, err := m.startDegreesRamp(degrees, speed)
+	if err != nil {
+		return err
+	}
+
+	if err := ramp.waitContext(ctx); err != nil {
+		m.brick.removeRampFuture(m.port.Int(), ramp.future)
+		if ctx.Err() != nil {
+			_ = m.Coast()
+		}
+		m.setRunMode(MotorRunModeNone)
+		return err
+	}
+
+	m.finishMove(ramp.target)
+
+	m.setRunMode(MotorRunModeNone)
+	return nil
+}
+
+// RunForDegreesAsync starts a relative move like RunForDegrees and returns once the ramp is
+// sent. The move finishes in the background; use WaitUntilStopped to wait for it.
+func (m *Motor) RunForDegreesAsync(degrees, speed int) error {
+	ramp, err := m.startDegreesRamp(degrees, speed)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := ramp.wait(); err != nil {
+			m.brick.removeRampFuture(m.port.Int(), ramp.future)
+			m.brick.logger.Warn("Async move failed", "port", m.port, "error", err)
+		} else {
+			m.finishMove(ramp.target)
+		}
+		m.setRunMode(MotorRunModeNone)
+	}()
+	return nil
+}
+
+// pendingRamp is a ramp sent to the HAT that is awaiting its "ramp done" message
+type pendingRamp struct {
+	brick   *Brick
+	future  chan bool
+	timeout time.Duration
+	target  float64 // target position in rotations
+}
+
+// wait blocks until the ramp completes or its timeout expires
+func (r pendingRamp) wait() error {
+	return r.waitContext(context.Background())
+}
+
+// waitContext blocks until the ramp completes, its timeout expires or ctx is done
+func (r pendingRamp) waitContext(ctx context.Context) error {
+	select {
+	case done := <-r.future:
+		if !done {
+			return ErrStopped
+		}
+		return nil
+	case <-time.After(r.timeout):
+		if r.brick != nil {
+			r.brick.metrics.timeouts.Add(1)
+		}
+		return fmt.Errorf("timeout waiting for ramp completion")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startDegreesRamp sends the ramp for a relative move without waiting for it to complete
+func (m *Motor) startDegreesRamp(degrees, speed int) (pendingRamp, error) {
+	cmd, ramp, err := m.planDegreesRamp(degrees, speed)
+	if err != nil {
+		return pendingRamp{}, err
+	}
+
+	m.setRunMode(MotorRunModeDegrees)
+	ramp.future = m.addRampFuture()
+
+	if err := m.brick.writeCommand(cmd); err != nil {
+		// Remove the future since command failed
+		m.brick.removeRampFuture(m.port.Int(), ramp.future)
+		return pendingRamp{}, err
+	}
+	return ramp, nil
+}
+
+// planDegreesRamp reads the start position and builds the ramp command for a relative move.
+// Nothing is sent and the returned ramp has no future yet, so several motors' ramps can be
+// planned first and sent back to back.
+func (m *Motor) planDegreesRamp(degrees, speed int) (Command, pendingRamp, error) {
 	if speed == 0 {
 		speed = m.defaultSpeed
 	}
 	if speed < -100 || speed > 100 {
-		return fmt.Errorf("invalid speed: must be between -100 and 100")
+		return nil, pendingRamp{}, fmt.Errorf("invalid speed: must be between -100 and 100")
 	}
 
-	m.runMode = MotorRunModeDegrees
-
-	// Get current position
-	position, err := m.GetPosition()
+	// Get current position, in the HAT's frame as the ramp setpoints are
+	position, err := m.rawPosition()
 	if err != nil {
 		// If we can't get position, use a simple approach
 		position = 0
@@ -130,46 +316,32 @@ func (m *Motor) RunForDegrees(degrees, speed int) error {
 		}
 	}
 
-	// Create a future channel for completion notification
+	// Ramp command (selrate 10 sets the sensor data interval)
+	cmd := m.controlled(m.positionPID()).SetRamp(currentPos, newPos, durationSecs).Build()
+	return cmd, pendingRamp{
+		brick:   m.brick,
+		timeout: time.Duration((durationSecs + 2.0) * float64(time.Second)), // Add 2 second buffer
+		target:  newPos,
+	}, nil
+}
+
+// addRampFuture registers a future for the port's next "ramp done" message
+func (m *Motor) addRampFuture() chan bool {
 	future := make(chan bool, 1)
 	m.brick.mu.Lock()
 	m.brick.rampFutures[m.port] = append(m.brick.rampFutures[m.port], future)
 	m.brick.mu.Unlock()
+	return future
+}
 
-	// Send ramp command (selrate 10 sets the sensor data interval)
-	if err := m.brick.writeCommand(Compound(
-		SelectPort(m.port),
-		Select(0),
-		SelRate(10),
-		PID(m.port.Int(), 0, 1, DataFormatS4, 0.0027777778, 0, 5, 0, 0.1, 3, 0.01),
-		SetRamp(currentPos, newPos, durationSecs),
-	)); err != nil {
-		// Remove the future since command failed
-		m.brick.mu.Lock()
-		if len(m.brick.rampFutures[m.port]) > 0 {
-			m.brick.rampFutures[m.port] = m.brick.rampFutures[m.port][:len(m.brick.rampFutures[m.port])-1]
-		}
-		m.brick.mu.Unlock()
-		return err
-	}
-
-	// Wait for ramp completion with timeout
-	timeout := time.Duration((durationSecs + 2.0) * float64(time.Second)) // Add 2 second buffer
-	select {
-	case <-future:
-		// Ramp completed successfully
-	case <-time.After(timeout):
-		return fmt.Errorf("timeout waiting for ramp completion")
-	}
-
-	// Coast to stop if release is enabled
+// finishMove coasts to stop if release is enabled, otherwise holds the target position
+func (m *Motor) finishMove(target float64) {
 	if m.release {
-		time.Sleep(200 * time.Millisecond)
+		time.Sleep(m.releaseDelay)
 		_ = m.Coast()
+	} else {
+		_ = m.holdAt(target)
 	}
-
-	m.runMode = MotorRunModeNone
-	return nil
 }
 
 // RunForDuration runs the motor for the specified duration
@@ -181,18 +353,15 @@ func (m *Motor) RunForDuration(duration time.Duration, speed int) error {
 		return fmt.Errorf("invalid speed: must be between -100 and 100")
 	}
 
-	m.runMode = MotorRunModeSeconds
+	m.setRunMode(MotorRunModeSeconds)
 
-	// Process speed (sent as-is, not multiplied by 0.05)
+	// Process speed (sent as-is, not multiplied by 0.05). In RPM units the setpoint is in
+	// rotations per second, matching the pid_diff process variable: position differentiated
+	// over time and scaled from degrees to rotations.
 	processedSpeed := m.processSpeed(speed)
 
 	// Set up PID for speed control
-	var pidCmd Command
-	if m.rpm {
-		pidCmd = PIDDiff(m.port.Int(), 0, 5, DataFormatS2, 0.0027777778, 1, 0, 2.5, 0, 0.4, 0.01)
-	} else {
-		pidCmd = PID(m.port.Int(), 0, 0, DataFormatS1, 1, 0, 0.003, 0.01, 0, 100, 0.01)
-	}
+	pidCmd := m.speedPID()
 
 	// Create a future channel for completion notification
 	future := make(chan bool, 1)
@@ -201,28 +370,25 @@ func (m *Motor) RunForDuration(duration time.Duration, speed int) error {
 	m.brick.mu.Unlock()
 
 	seconds := duration.Seconds()
-	if err := m.brick.writeCommand(Compound(
-		SelectPort(m.port),
-		Select(0),
-		SelRate(10),
-		pidCmd,
-		SetPulse(processedSpeed, 0.0, seconds),
-	)); err != nil {
+	if err := m.brick.writeCommand(m.controlled(pidCmd).SetPulse(processedSpeed, 0.0, seconds).Build()); err != nil {
 		// Remove the future since command failed
-		m.brick.mu.Lock()
-		if len(m.brick.pulseFutures[m.port]) > 0 {
-			m.brick.pulseFutures[m.port] = m.brick.pulseFutures[m.port][:len(m.brick.pulseFutures[m.port])-1]
-		}
-		m.brick.mu.Unlock()
+		m.brick.removePulseFuture(m.port.Int(), future)
+		m.setRunMode(MotorRunModeNone)
 		return err
 	}
 
 	// Wait for pulse completion with timeout
 	timeout := duration + 2*time.Second // Add 2 second buffer
 	select {
-	case <-future:
-		// Pulse completed successfully
+	case done := <-future:
+		if !done {
+			m.setRunMode(MotorRunModeNone)
+			return ErrStopped
+		}
 	case <-time.After(timeout):
+		m.brick.removePulseFuture(m.port.Int(), future)
+		m.brick.metrics.timeouts.Add(1)
+		m.setRunMode(MotorRunModeNone)
 		return fmt.Errorf("timeout waiting for pulse completion")
 	}
 
@@ -231,7 +397,7 @@ func (m *Motor) RunForDuration(duration time.Duration, speed int) error {
 		_ = m.Coast()
 	}
 
-	m.runMode = MotorRunModeNone
+	m.setRunMode(MotorRunModeNone)
 	return nil
 }
 
@@ -241,10 +407,11 @@ func (m *Motor) RunToPosition(degrees, speed int, direction MotorDirection) erro
 		return err
 	}
 
-	m.runMode = MotorRunModeDegrees
+	m.setRunMode(MotorRunModeDegrees)
 
 	pos, apos, err := m.getCurrentAndAbsolutePosition()
 	if err != nil {
+		m.setRunMode(MotorRunModeNone)
 		return err
 	}
 
@@ -253,14 +420,16 @@ func (m *Motor) RunToPosition(degrees, speed int, direction MotorDirection) erro
 	duration := m.calculateMovementDuration(currentPosRotations, newPos, speed)
 
 	if err := m.executeRampMovement(currentPosRotations, newPos, duration); err != nil {
+		m.setRunMode(MotorRunModeNone)
 		return err
 	}
 
-	if err := m.waitForMovementCompletion(); err != nil {
+	if err := m.waitForMovementCompletion(newPos); err != nil {
+		m.setRunMode(MotorRunModeNone)
 		return err
 	}
 
-	m.runMode = MotorRunModeNone
+	m.setRunMode(MotorRunModeNone)
 	return nil
 }
 
@@ -279,15 +448,42 @@ func (m *Motor) validatePositionParams(degrees, speed int, direction MotorDirect
 }
 
 // getCurrentAndAbsolutePosition retrieves current motor position data
+// The combi stream can briefly send short samples right after it's configured, so those are
+// read again up to the configured retries before failing.
 func (m *Motor) getCurrentAndAbsolutePosition() (pos, apos int, err error) {
-	data, err := m.getData()
-	if err != nil {
-		return 0, 0, err
+	deadline := time.Now().Add(m.dataTimeout)
+	for attempt := 0; ; attempt++ {
+		data, err := m.getData()
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(data) >= 3 {
+			pos, posOK := data[1].(int)
+			apos, aposOK := data[2].(int)
+			if !posOK || !aposOK {
+				return 0, 0, fmt.Errorf("invalid position data type")
+			}
+			return pos, apos, nil
+		}
+		if attempt >= m.dataRetries || time.Now().After(deadline) {
+			return 0, 0, fmt.Errorf("insufficient motor data")
+		}
 	}
-	if len(data) < 3 {
-		return 0, 0, fmt.Errorf("insufficient motor data")
+}
+
+// SetPartialDataRetries sets how many more samples are read when the motor sends fewer values
+// than position moves need, and how long those extra reads may take in total. The defaults are
+// 3 retries within 500ms; 0 retries fails on the first short sample.
+func (m *Motor) SetPartialDataRetries(retries int, timeout time.Duration) error {
+	if retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
 	}
-	return data[1].(int), data[2].(int), nil
+	m.dataRetries = retries
+	m.dataTimeout = timeout
+	return nil
 }
 
 // calculateTargetPosition calculates the target position based on direction
@@ -352,55 +548,136 @@ func (m *Motor) calculateMovementDuration(currentPos, newPos float64, speed int)
 	return time.Duration(durationSecs * float64(time.Second))
 }
 
-// executeRampMovement sends the ramp command to the motor
+// executeRampMovement sends the ramp command to the motor and waits for it to complete
 func (m *Motor) executeRampMovement(currentPos, newPos float64, duration time.Duration) error {
-	// Create a future channel for completion notification
-	future := make(chan bool, 1)
-	m.brick.mu.Lock()
-	m.brick.rampFutures[m.port] = append(m.brick.rampFutures[m.port], future)
-	m.brick.mu.Unlock()
+	future := m.addRampFuture()
 
 	durationSecs := duration.Seconds()
-	if err := m.brick.writeCommand(Compound(
-		SelectPort(m.port),
-		Select(0),
-		SelRate(10),
-		PID(m.port.Int(), 0, 1, DataFormatS4, 0.0027777778, 0, 5, 0, 0.1, 3, 0.01),
-		SetRamp(currentPos, newPos, durationSecs),
-	)); err != nil {
+	if err := m.brick.writeCommand(m.controlled(m.positionPID()).SetRamp(currentPos, newPos, durationSecs).Build()); err != nil {
 		// Remove the future since command failed
-		m.brick.mu.Lock()
-		if len(m.brick.rampFutures[m.port]) > 0 {
-			m.brick.rampFutures[m.port] = m.brick.rampFutures[m.port][:len(m.brick.rampFutures[m.port])-1]
-		}
-		m.brick.mu.Unlock()
+		m.brick.removeRampFuture(m.port.Int(), future)
 		return err
 	}
 
-	// Wait for ramp completion with timeout
-	timeout := duration + 2*time.Second // Add 2 second buffer
-	select {
-	case <-future:
-		return nil
-	case <-time.After(timeout):
-		return fmt.Errorf("timeout waiting for ramp completion")
+	ramp := pendingRamp{
+		brick:   m.brick,
+		future:  future,
+		timeout: duration + 2*time.Second, // Add 2 second buffer
+		target:  newPos,
+	}
+	if err := ramp.wait(); err != nil {
+		m.brick.removeRampFuture(m.port.Int(), future)
+		return err
 	}
+	return nil
 }
 
-// waitForMovementCompletion handles post-movement coast if release is enabled
-func (m *Motor) waitForMovementCompletion() error {
+// waitForMovementCompletion handles the post-movement coast if release is enabled,
+// or holds the target position (in rotations) otherwise
+func (m *Motor) waitForMovementCompletion(target float64) error {
 	// The executeRampMovement function now handles waiting for completion
-	// This function just handles the post-movement coast
+	// This function just handles the post-movement coast or hold
 	if m.release {
-		time.Sleep(200 * time.Millisecond)
-		if err := m.Coast(); err != nil {
-			return err
-		}
+		time.Sleep(m.releaseDelay)
+		return m.Coast()
+	}
+
+	return m.holdAt(target)
+}
+
+// positionPID returns the PID controller used for position moves and holds.
+// The process variable is the position (mode 0, offset 1, s4) scaled from degrees
+// to rotations. kp and kd are scaled by the ramp profile.
+func (m *Motor) positionPID() Command {
+	g := m.positionGains
+	return PID(m.port.Int(), 0, 1, DataFormatS4, 0.0027777778, 0, g.Kp*m.accel, g.Ki, g.Kd*m.decel, g.Windup, g.Bias)
+}
+
+// speedPID returns the PID controller used for Start and RunForDuration. In RPM units it
+// differentiates the position (mode 0, offset 5, s2); otherwise it follows the speed
+// reading (mode 0, offset 0, s1) directly.
+func (m *Motor) speedPID() Command {
+	if m.rpm {
+		g := m.rpmGains
+		return PIDDiff(m.port.Int(), 0, 5, DataFormatS2, 0.0027777778, 1, g.Kp, g.Ki, g.Kd, g.Windup, g.Bias)
+	}
+	g := m.speedGains
+	return PID(m.port.Int(), 0, 0, DataFormatS1, 1, 0, g.Kp, g.Ki, g.Kd, g.Windup, g.Bias)
+}
+
+// SetPositionPID sets the gains of the controller used for position moves and holds.
+// The defaults are kp=5, ki=0, kd=0.1, windup=3, bias=0.01.
+func (m *Motor) SetPositionPID(kp, ki, kd, windup, bias float64) error {
+	gains := PIDGains{Kp: kp, Ki: ki, Kd: kd, Windup: windup, Bias: bias}
+	if err := gains.validate(); err != nil {
+		return err
+	}
+	m.positionGains = gains
+	return nil
+}
+
+// SetSpeedPID sets the gains of the speed controller for the current speed unit
+// (see SetSpeedUnitRPM); each unit keeps its own gains. The defaults are
+// kp=0.003, ki=0.01, kd=0, windup=100, bias=0.01 for percent speed and
+// kp=0, ki=2.5, kd=0, windup=0.4, bias=0.01 for RPM.
+func (m *Motor) SetSpeedPID(kp, ki, kd, windup, bias float64) error {
+	gains := PIDGains{Kp: kp, Ki: ki, Kd: kd, Windup: windup, Bias: bias}
+	if err := gains.validate(); err != nil {
+		return err
+	}
+	if m.rpm {
+		m.rpmGains = gains
+	} else {
+		m.speedGains = gains
+	}
+	return nil
+}
+
+// validate rejects gains the controller can't use
+func (g PIDGains) validate() error {
+	if g.Kp < 0 || g.Ki < 0 || g.Kd < 0 {
+		return fmt.Errorf("pid gains must not be negative")
+	}
+	if g.Windup < 0 {
+		return fmt.Errorf("pid windup must not be negative")
 	}
+	return nil
+}
 
+// SetRampProfile tunes how the motor follows position ramps. accel scales the proportional
+// gain, so higher values chase the setpoint harder; decel scales the derivative gain, so
+// higher values brake harder as the motor approaches the target. The default of 1, 1 keeps
+// the stock kp=5, kd=0.1.
+func (m *Motor) SetRampProfile(accel, decel float64) error {
+	if accel <= 0 || decel <= 0 {
+		return fmt.Errorf("ramp profile values must be positive")
+	}
+	m.accel = accel
+	m.decel = decel
 	return nil
 }
 
+// Hold actively holds the motor at its current position using the position PID,
+// so it resists being back-driven instead of coasting
+func (m *Motor) Hold() error {
+	position, err := m.rawPosition()
+	if err != nil {
+		return err
+	}
+	return m.holdAt(float64(position) / 360.0)
+}
+
+// holdAt sets a constant position setpoint (in rotations) under the position PID
+func (m *Motor) holdAt(rotations float64) error {
+	return m.brick.writeCommand(m.controlled(m.positionPID()).SetConstantFormatted(rotations, "%f").Build())
+}
+
+// controlled starts a command for the motor's port that streams mode 0 every 10ms
+// into the given controller, ready for a setpoint
+func (m *Motor) controlled(pid Command) *CommandBuilder {
+	return NewCommandBuilder().Port(m.port).Select(0).SelRate(10).PID(pid)
+}
+
 // Start starts the motor at the specified speed
 func (m *Motor) Start(speed int) error {
 	if speed == 0 {
@@ -411,12 +688,12 @@ func (m *Motor) Start(speed int) error {
 	}
 
 	// If already running at this speed, do nothing
-	if m.runMode == MotorRunModeFree && m.currentSpeed == speed {
+	if m.RunMode() == MotorRunModeFree && m.currentSpeed == speed {
 		return nil
 	}
 
 	// If motor is running in another mode, don't interrupt
-	if m.runMode != MotorRunModeNone && m.runMode != MotorRunModeFree {
+	if mode := m.RunMode(); mode != MotorRunModeNone && mode != MotorRunModeFree {
 		return fmt.Errorf("motor is busy in another mode")
 	}
 
@@ -424,31 +701,161 @@ func (m *Motor) Start(speed int) error {
 	processedSpeed := m.processSpeed(speed)
 
 	// Set up PID
-	var pidCmd Command
-	if m.rpm {
-		pidCmd = PIDDiff(m.port.Int(), 0, 5, DataFormatS2, 0.0027777778, 1, 0, 2.5, 0, 0.4, 0.01)
-	} else {
-		pidCmd = PID(m.port.Int(), 0, 0, DataFormatS1, 1, 0, 0.003, 0.01, 0, 100, 0.01)
-	}
+	pidCmd := m.speedPID()
 
-	if err := m.brick.writeCommand(Compound(
-		SelectPort(m.port),
-		Select(0),
-		SelRate(10),
-		pidCmd,
-		SetConstantFormatted(processedSpeed, "%f"),
-	)); err != nil {
+	if err := m.brick.writeCommand(m.controlled(pidCmd).SetConstantFormatted(processedSpeed, "%f").Build()); err != nil {
 		return err
 	}
 
-	m.runMode = MotorRunModeFree
+	m.setRunMode(MotorRunModeFree)
 	m.currentSpeed = speed
 	return nil
 }
 
+// RampSpeed changes the free-run speed gradually from the current one (0 when stopped) to
+// target over duration, ramping the speed controller's setpoint rather than stepping it as
+// Start does, which limits current spikes. It returns once the ramp is done, leaving the motor
+// running at target; a target of 0 holds the motor still until Stop or Coast. If the ramp is
+// interrupted or times out, the speed reached is unknown and the motor is treated as idle.
+func (m *Motor) RampSpeed(target int, duration time.Duration) error {
+	if target < -100 || target > 100 {
+		return fmt.Errorf("invalid speed: must be between -100 and 100")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("ramp duration must be positive")
+	}
+
+	from := 0
+	switch m.RunMode() {
+	case MotorRunModeFree:
+		from = m.currentSpeed
+	case MotorRunModeNone:
+	default:
+		return fmt.Errorf("motor is busy in another mode")
+	}
+
+	future := make(chan bool, 1)
+	m.brick.mu.Lock()
+	m.brick.rampFutures[m.port] = append(m.brick.rampFutures[m.port], future)
+	m.brick.mu.Unlock()
+
+	cmd := m.controlled(m.speedPID()).SetRamp(m.processSpeed(from), m.processSpeed(target), duration.Seconds()).Build()
+	if err := m.brick.writeCommand(cmd); err != nil {
+		m.brick.removeRampFuture(m.port.Int(), future)
+		return err
+	}
+	m.setRunMode(MotorRunModeFree)
+
+	select {
+	case done := <-future:
+		if done {
+			m.currentSpeed = target
+			return nil
+		}
+		m.currentSpeed = 0
+		m.setRunMode(MotorRunModeNone)
+		return ErrStopped
+	case <-time.After(duration + 2*time.Second):
+		m.brick.removeRampFuture(m.port.Int(), future)
+		m.brick.metrics.timeouts.Add(1)
+		m.currentSpeed = 0
+		m.setRunMode(MotorRunModeNone)
+		return fmt.Errorf("timeout waiting for ramp completion")
+	}
+}
+
+// RunUntil runs the motor at speed until stop returns true, stop fails or ctx is cancelled,
+// then coasts. stop is first checked right after starting and then every poll interval
+// (see SetPollInterval). It returns stop's error or ctx.Err(), if any.
+func (m *Motor) RunUntil(ctx context.Context, speed int, stop func() (bool, error)) error {
+	if stop == nil {
+		return fmt.Errorf("stop condition must not be nil")
+	}
+	if err := m.Start(speed); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := stop()
+		if err != nil || done {
+			if stopErr := m.Stop(); err == nil {
+				err = stopErr
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := m.Stop(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetPollInterval sets how often RunUntil checks its stop condition. The default is 50ms.
+func (m *Motor) SetPollInterval(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+	m.pollInterval = d
+	return nil
+}
+
+// RunMode returns what the motor is currently doing
+func (m *Motor) RunMode() MotorRunMode {
+	m.brick.mu.RLock()
+	defer m.brick.mu.RUnlock()
+	return m.runMode
+}
+
+// IsMoving reports whether the motor is running freely or in the middle of a movement
+func (m *Motor) IsMoving() bool {
+	return m.RunMode() != MotorRunModeNone
+}
+
+// setRunMode records what the motor is doing, under the brick mutex. Leaving or
+// returning to MotorRunModeNone opens or closes the channel WaitUntilStopped waits on.
+func (m *Motor) setRunMode(mode MotorRunMode) {
+	m.brick.mu.Lock()
+	defer m.brick.mu.Unlock()
+
+	switch {
+	case mode == MotorRunModeNone && m.stopped != nil:
+		close(m.stopped)
+		m.stopped = nil
+	case mode != MotorRunModeNone && m.stopped == nil:
+		m.stopped = make(chan struct{})
+	}
+	m.runMode = mode
+}
+
+// WaitUntilStopped blocks until the current movement completes, returning immediately if
+// the motor is idle. A motor running freely after Start only stops with Stop.
+func (m *Motor) WaitUntilStopped(ctx context.Context) error {
+	m.brick.mu.RLock()
+	stopped := m.stopped
+	m.brick.mu.RUnlock()
+
+	if stopped == nil {
+		return nil
+	}
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Stop stops the motor
 func (m *Motor) Stop() error {
-	m.runMode = MotorRunModeNone
+	m.setRunMode(MotorRunModeNone)
 	m.currentSpeed = 0
 	return m.Coast()
 }
@@ -463,8 +870,19 @@ func (m *Motor) Float() error {
 	return m.Coast()
 }
 
-// GetPosition gets the position of motor relative to preset position
+// GetPosition gets the position of motor relative to preset position, including the
+// offset set by SetPositionTo
 func (m *Motor) GetPosition() (int, error) {
+	pos, err := m.rawPosition()
+	if err != nil {
+		return 0, err
+	}
+	return pos + int(m.positionOffset.Load()), nil
+}
+
+// rawPosition gets the HAT's position counter, without the SetPositionTo offset. Position
+// setpoints sent to the HAT are in this frame.
+func (m *Motor) rawPosition() (int, error) {
 	data, err := m.getData()
 	if err != nil {
 		return 0, err
@@ -493,6 +911,31 @@ func (m *Motor) GetAbsolutePosition() (int, error) {
 	return 0, fmt.Errorf("invalid absolute position data type")
 }
 
+// GetRevolutions gets the number of revolutions traveled since the preset position, from
+// GetPosition, e.g. 2.5 after 900 degrees. The HAT reports the position as a 32-bit count of
+// degrees; samples beyond that range, about 5.9 million revolutions either way, are rejected
+// as invalid.
+func (m *Motor) GetRevolutions() (float64, error) {
+	pos, err := m.GetPosition()
+	if err != nil {
+		return 0, err
+	}
+	return float64(pos) / 360.0, nil
+}
+
+// GetContinuousAngle gets an unbounded angle in degrees that follows the absolute position:
+// the number of full turns is taken from the position and the angle within the turn from the
+// absolute position, so it keeps counting past the -180/180 wrap, e.g. 190 rather than -170.
+// The SetPositionTo offset is not applied. The motor must report an absolute position.
+func (m *Motor) GetContinuousAngle() (int, error) {
+	pos, apos, err := m.getCurrentAndAbsolutePosition()
+	if err != nil {
+		return 0, err
+	}
+	turns := math.Round(float64(pos-apos) / 360.0)
+	return int(turns)*360 + apos, nil
+}
+
 // GetSpeed gets the current speed of the motor
 func (m *Motor) GetSpeed() (int, error) {
 	data, err := m.getData()
@@ -513,7 +956,30 @@ func (m *Motor) SetPowerLimit(limit float64) error {
 	if limit < 0 || limit > 1 {
 		return fmt.Errorf("power limit must be between 0 and 1")
 	}
-	return m.brick.writeCommand(Compound(SelectPort(m.port), PortPLimit(limit)))
+	if err := m.brick.writeCommand(Compound(SelectPort(m.port), PortPLimit(limit))); err != nil {
+		return err
+	}
+	m.powerLimit = limit
+	return nil
+}
+
+// GetPowerLimit returns the power limit last applied with SetPowerLimit
+func (m *Motor) GetPowerLimit() float64 {
+	return m.powerLimit
+}
+
+// SetBias sets the drive bias of the motor (0.0 to 1.0)
+func (m *Motor) SetBias(bias float64) error {
+	if err := m.brick.SetMotorBias(m.port, bias); err != nil {
+		return err
+	}
+	m.bias = bias
+	return nil
+}
+
+// GetBias returns the bias last applied with SetBias
+func (m *Motor) GetBias() float64 {
+	return m.bias
 }
 
 // SetPWMParams sets PWM thresholds
@@ -524,7 +990,17 @@ func (m *Motor) SetPWMParams(pwmThresh, minPWM float64) error {
 	if minPWM < 0 || minPWM > 1 {
 		return fmt.Errorf("minPWM must be between 0 and 1")
 	}
-	return m.brick.writeCommand(Compound(SelectPort(m.port), PWMParams(pwmThresh, minPWM)))
+	if err := m.brick.writeCommand(Compound(SelectPort(m.port), PWMParams(pwmThresh, minPWM))); err != nil {
+		return err
+	}
+	m.pwmThresh = pwmThresh
+	m.minPWM = minPWM
+	return nil
+}
+
+// GetPWMParams returns the PWM thresholds last applied with SetPWMParams
+func (m *Motor) GetPWMParams() (pwmThresh, minPWM float64) {
+	return m.pwmThresh, m.minPWM
 }
 
 // PWM sets the motor to PWM mode with the specified value (-1.0 to 1.0)
@@ -537,14 +1013,132 @@ func (m *Motor) PWM(value float64) error {
 
 // PresetPosition presets the motor position to 0
 func (m *Motor) PresetPosition() error {
-	return m.brick.writeCommand(Compound(SelectPort(m.port), Preset()))
+	return m.SetPositionTo(0)
 }
 
-// SetRelease sets whether the motor should coast after completing a movement
+// SetPositionTo declares the motor's current position to be degrees. The HAT can only preset
+// its position counter to 0, so this presets it and then keeps degrees as an offset that
+// GetPosition and OnData add to the reported position. Position setpoints sent to the HAT,
+// by relative moves and Hold, stay in the HAT's own frame, and RunToPosition works from the
+// absolute position, so none of them move the motor because of the offset.
+func (m *Motor) SetPositionTo(degrees int) error {
+	if err := m.brick.writeCommand(Compound(SelectPort(m.port), Preset())); err != nil {
+		return err
+	}
+	m.positionOffset.Store(int64(degrees))
+	return nil
+}
+
+// SetReleaseDelay sets how long the motor settles after a move before coasting.
+// A zero delay coasts immediately. The default is 200ms.
+func (m *Motor) SetReleaseDelay(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("release delay must not be negative")
+	}
+	m.releaseDelay = d
+	return nil
+}
+
+// SetRelease sets whether the motor should coast after completing a movement.
+// When release is disabled, movements end by holding the target position (see Hold).
 func (m *Motor) SetRelease(release bool) {
 	m.release = release
 }
 
+// MotorData is one combi sample from a motor
+type MotorData struct {
+	Speed            int  // Speed in percent
+	Position         int  // Position in degrees relative to the preset position
+	AbsolutePosition int  // Absolute position in degrees (-180 to 180)
+	HasAbsolute      bool // False for motors that don't report an absolute position
+}
+
+// OnData calls fn with every combi sample the motor streams, decoded and in the order
+// received, e.g. to drive a velocity control loop. fn is called from a streaming goroutine;
+// samples arriving while it's busy are buffered and dropped once the buffer is full. Call
+// the returned function to stop.
+func (m *Motor) OnData(fn func(MotorData)) func() {
+	if fn == nil {
+		return func() {}
+	}
+
+	readings, unsubscribe := m.brick.Subscribe(m.port)
+	go func() {
+		for data := range readings {
+			if sample, ok := decodeMotorData(data); ok {
+				sample.Position += int(m.positionOffset.Load())
+				fn(sample)
+			}
+		}
+	}()
+
+	return unsubscribe
+}
+
+// StreamCSV writes the motor's combi samples to w as CSV, with a
+// "timestamp,speed,position,absPosition" header, until ctx is done, then returns ctx.Err().
+// Timestamps are RFC 3339 with nanoseconds, taken when each sample is received; absPosition
+// is empty for motors without an absolute position.
+func (m *Motor) StreamCSV(ctx context.Context, w io.Writer) error {
+	readings, unsubscribe := m.brick.Subscribe(m.port)
+	defer unsubscribe()
+
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"timestamp", "speed", "position", "absPosition"}); err != nil {
+		return err
+	}
+	out.Flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data := <-readings:
+			sample, ok := decodeMotorData(data)
+			if !ok {
+				continue
+			}
+			sample.Position += int(m.positionOffset.Load())
+
+			absPosition := ""
+			if sample.HasAbsolute {
+				absPosition = strconv.Itoa(sample.AbsolutePosition)
+			}
+			row := []string{
+				time.Now().Format(time.RFC3339Nano),
+				strconv.Itoa(sample.Speed),
+				strconv.Itoa(sample.Position),
+				absPosition,
+			}
+			if err := out.Write(row); err != nil {
+				return err
+			}
+			out.Flush()
+			if err := out.Error(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeMotorData decodes a combi sample of speed, position and absolute position
+func decodeMotorData(data []any) (MotorData, bool) {
+	if len(data) < 2 {
+		return MotorData{}, false
+	}
+	speed, speedOK := data[0].(int)
+	pos, posOK := data[1].(int)
+	if !speedOK || !posOK {
+		return MotorData{}, false
+	}
+
+	sample := MotorData{Speed: speed, Position: pos}
+	if len(data) >= 3 {
+		sample.AbsolutePosition, sample.HasAbsolute = data[2].(int)
+	}
+	return sample, true
+}
+
 // getData gets the current motor data (speed, position, absolute position)
 // Note: Unlike sending a command, this just waits for the next data packet
 // from the motor. The motor continuously sends data due to combi mode setup.
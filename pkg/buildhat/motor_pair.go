@@ -0,0 +1,127 @@
+package buildhat
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MotorPair creates an interface driving two motors together, e.g. for a differential-drive robot
+func (b *Brick) MotorPair(left, right Port) *MotorPair {
+	return &MotorPair{
+		brick: b,
+		left:  b.Motor(left),
+		right: b.Motor(right),
+	}
+}
+
+// MotorPair provides a Python-like motor pair interface
+type MotorPair struct {
+	brick *Brick
+	left  *Motor
+	right *Motor
+}
+
+// Left returns the left motor of the pair
+func (p *MotorPair) Left() *Motor {
+	return p.left
+}
+
+// Right returns the right motor of the pair
+func (p *MotorPair) Right() *Motor {
+	return p.right
+}
+
+// RunForDegrees runs both motors for the specified number of degrees.
+// Both start positions are read first, then the two ramps are sent in a single write so the
+// motors start together. The call returns once both ramps have completed.
+func (p *MotorPair) RunForDegrees(degrees, speed int) error {
+	leftCmd, leftRamp, err := p.left.planDegreesRamp(degrees, speed)
+	if err != nil {
+		return fmt.Errorf("left motor: %w", err)
+	}
+	rightCmd, rightRamp, err := p.right.planDegreesRamp(degrees, speed)
+	if err != nil {
+		return fmt.Errorf("right motor: %w", err)
+	}
+
+	p.left.setRunMode(MotorRunModeDegrees)
+	p.right.setRunMode(MotorRunModeDegrees)
+	leftRamp.future = p.left.addRampFuture()
+	rightRamp.future = p.right.addRampFuture()
+
+	if err := p.brick.writeCommand(Compound(leftCmd, rightCmd)); err != nil {
+		p.abort(leftRamp, rightRamp, true)
+		return err
+	}
+
+	// Wait for both at once so a timeout on either side takes no longer than one ramp's
+	rightDone := make(chan error, 1)
+	go func() { rightDone <- rightRamp.wait() }()
+	if err := errors.Join(leftRamp.wait(), <-rightDone); err != nil {
+		// StopAll has already coasted both motors; after a timeout don't leave one side driving
+		p.abort(leftRamp, rightRamp, !errors.Is(err, ErrStopped))
+		return err
+	}
+
+	// Release or hold both motors at the same time
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.left.finishMove(leftRamp.target)
+	}()
+	go func() {
+		defer wg.Done()
+		p.right.finishMove(rightRamp.target)
+	}()
+	wg.Wait()
+
+	p.left.setRunMode(MotorRunModeNone)
+	p.right.setRunMode(MotorRunModeNone)
+	return nil
+}
+
+// abort unregisters both ramps of a failed move so they cannot take a later "ramp done",
+// optionally coasts both motors, and marks them idle
+func (p *MotorPair) abort(leftRamp, rightRamp pendingRamp, coast bool) {
+	p.brick.removeRampFuture(p.left.port.Int(), leftRamp.future)
+	p.brick.removeRampFuture(p.right.port.Int(), rightRamp.future)
+	if coast {
+		_ = p.brick.writeCommand(Compound(SelectPort(p.left.port), Coast(), SelectPort(p.right.port), Coast()))
+	}
+	p.left.setRunMode(MotorRunModeNone)
+	p.right.setRunMode(MotorRunModeNone)
+}
+
+// Start starts both motors at their own speed (-100 to 100)
+func (p *MotorPair) Start(leftSpeed, rightSpeed int) error {
+	if err := p.left.Start(leftSpeed); err != nil {
+		return fmt.Errorf("left motor: %w", err)
+	}
+	if err := p.right.Start(rightSpeed); err != nil {
+		// Don't leave the left motor driving on its own
+		_ = p.left.Stop()
+		return fmt.Errorf("right motor: %w", err)
+	}
+	return nil
+}
+
+// Stop stops both motors
+func (p *MotorPair) Stop() error {
+	return errors.Join(p.left.Stop(), p.right.Stop())
+}
+
+// SetBias sets the motor drive bias of each side (0.0 to 1.0)
+func (p *MotorPair) SetBias(leftBias, rightBias float64) error {
+	if leftBias < 0 || leftBias > 1 || rightBias < 0 || rightBias > 1 {
+		return fmt.Errorf("bias must be between 0 and 1")
+	}
+	if err := p.left.SetBias(leftBias); err != nil {
+		return fmt.Errorf("left motor: %w", err)
+	}
+	if err := p.right.SetBias(rightBias); err != nil {
+		return fmt.Errorf("right motor: %w", err)
+	}
+	return nil
+}
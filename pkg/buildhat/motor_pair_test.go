@@ -0,0 +1,213 @@
+package buildhat
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMotorPair_RunForDegrees(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	mockPort.SimulateSensorResponse("1", 0, "0 0 0")
+
+	pair := brick.MotorPair(PortA, PortB)
+	mockPort.ClearWriteHistory()
+
+	if err := pair.RunForDegrees(360, 50); err != nil {
+		t.Fatalf("RunForDegrees failed: %v", err)
+	}
+
+	// Both ramps go out together in the first write, after both positions were read
+	writeHistory := mockPort.GetWriteHistory()
+	ramp := func(port string) string {
+		return "port " + port + " ; select 0 ; selrate 10 ; pid " + port + " 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set ramp 0.000000 1.000000 0.400000 0"
+	}
+	expectedRamps := ramp("0") + " ; " + ramp("1") + "\r"
+	if len(writeHistory) == 0 || writeHistory[0] != expectedRamps {
+		t.Fatalf("Expected both ramps in one first write %q, got: %v", expectedRamps, writeHistory)
+	}
+	rampWrites := 0
+	for _, cmd := range writeHistory {
+		if strings.Contains(cmd, "set ramp") {
+			rampWrites++
+		}
+	}
+	if rampWrites != 1 {
+		t.Errorf("Expected a single ramp write, got %d: %v", rampWrites, writeHistory)
+	}
+
+	for _, port := range []string{"0", "1"} {
+		coast := "port " + port + " ; coast\r"
+		if !slices.Contains(writeHistory, coast) {
+			t.Errorf("Expected coast command %q, got: %v", coast, writeHistory)
+		}
+	}
+
+	if pair.Left().runMode != MotorRunModeNone || pair.Right().runMode != MotorRunModeNone {
+		t.Error("Expected both motors to be idle after the move")
+	}
+}
+
+func TestMotorPair_StartStop(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	pair := brick.MotorPair(PortA, PortB)
+	mockPort.ClearWriteHistory()
+
+	if err := pair.Start(30, -30); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	writeHistory := mockPort.GetWriteHistory()
+	expectedLeft := "port 0 ; select 0 ; selrate 10 ; pid 0 0 0 s1 1 0 0.003 0.01 0 100 0.01 ; set 30.000000\r"
+	expectedRight := "port 1 ; select 0 ; selrate 10 ; pid 1 0 0 s1 1 0 0.003 0.01 0 100 0.01 ; set -30.000000\r"
+	if !slices.Equal(writeHistory, []string{expectedLeft, expectedRight}) {
+		t.Errorf("Expected start commands %q and %q, got: %v", expectedLeft, expectedRight, writeHistory)
+	}
+
+	mockPort.ClearWriteHistory()
+	if err := pair.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	writeHistory = mockPort.GetWriteHistory()
+	if !slices.Equal(writeHistory, []string{"port 0 ; coast\r", "port 1 ; coast\r"}) {
+		t.Errorf("Expected coast on both ports, got: %v", writeHistory)
+	}
+}
+
+func TestMotorPair_SetBias(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	pair := brick.MotorPair(PortC, PortD)
+	mockPort.ClearWriteHistory()
+
+	if err := pair.SetBias(0.2, 0.3); err != nil {
+		t.Fatalf("SetBias failed: %v", err)
+	}
+
+	writeHistory := mockPort.GetWriteHistory()
+	if !slices.Equal(writeHistory, []string{"port 2 ; bias 0.2\r", "port 3 ; bias 0.3\r"}) {
+		t.Errorf("Unexpected bias commands: %v", writeHistory)
+	}
+	if left, right := pair.Left().GetBias(), pair.Right().GetBias(); left != 0.2 || right != 0.3 {
+		t.Errorf("Expected biases 0.2 and 0.3 to be read back, got %g and %g", left, right)
+	}
+
+	mockPort.ClearWriteHistory()
+	if err := pair.SetBias(0.5, 1.5); err == nil {
+		t.Error("Expected error for bias > 1")
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 0 {
+		t.Errorf("Expected no bias commands for an invalid bias, got %v", history)
+	}
+	if left := pair.Left().GetBias(); left != 0.2 {
+		t.Errorf("Expected the left bias to stay 0.2, got %g", left)
+	}
+}
+
+// failingWriter passes writes through to the mock port except those containing match
+type failingWriter struct {
+	port  *MockSerialPort
+	match string
+}
+
+func (w failingWriter) Write(data []byte) (int, error) {
+	if strings.Contains(string(data), w.match) {
+		return 0, errors.New("write failed")
+	}
+	return w.port.Write(data)
+}
+
+func TestMotorPair_RunForDegrees_WriteFails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+	brick := NewBrick(mockPort, failingWriter{port: mockPort, match: "set ramp"}, logger)
+	defer brick.Close()
+
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	mockPort.SimulateSensorResponse("1", 0, "0 0 0")
+
+	pair := brick.MotorPair(PortA, PortB)
+	mockPort.ClearWriteHistory()
+
+	if err := pair.RunForDegrees(360, 50); err == nil {
+		t.Fatal("Expected an error when the ramps cannot be sent")
+	}
+
+	if !slices.Contains(mockPort.GetWriteHistory(), "port 0 ; coast ; port 1 ; coast\r") {
+		t.Errorf("Expected both motors to coast, got: %v", mockPort.GetWriteHistory())
+	}
+	assertPairIdle(t, brick, pair)
+}
+
+func TestMotorPair_RunForDegrees_Timeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+	// Writes bypass the mock so no "ramp done" is sent back
+	output := &safeBuffer{}
+	brick := NewBrick(mockPort, output, logger)
+	defer brick.Close()
+
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	mockPort.SimulateSensorResponse("1", 0, "0 0 0")
+
+	pair := brick.MotorPair(PortA, PortB)
+	if err := pair.RunForDegrees(10, 100); err == nil {
+		t.Fatal("Expected a timeout without ramp done messages")
+	}
+
+	if !strings.HasSuffix(output.String(), "port 0 ; coast ; port 1 ; coast\r") {
+		t.Errorf("Expected both motors to coast after the timeout, got %q", output.String())
+	}
+	assertPairIdle(t, brick, pair)
+}
+
+// assertPairIdle checks both motors of the pair are idle with no ramp futures left behind
+func assertPairIdle(t *testing.T, brick *Brick, pair *MotorPair) {
+	t.Helper()
+
+	if mode := pair.Left().RunMode(); mode != MotorRunModeNone {
+		t.Errorf("Expected left run mode None, got %v", mode)
+	}
+	if mode := pair.Right().RunMode(); mode != MotorRunModeNone {
+		t.Errorf("Expected right run mode None, got %v", mode)
+	}
+
+	brick.mu.RLock()
+	defer brick.mu.RUnlock()
+	for _, port := range []Port{PortA, PortB} {
+		if pending := len(brick.rampFutures[port]); pending != 0 {
+			t.Errorf("Expected no pending ramp futures on port %s, got %d", port, pending)
+		}
+	}
+}
+
+func TestMotorPair_Start_RightFails(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	pair := brick.MotorPair(PortA, PortB)
+
+	if err := pair.Start(30, 101); err == nil {
+		t.Fatal("Expected an error for the right motor's speed")
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 0 ; coast\r" {
+		t.Errorf("Expected the left motor to be stopped, got %q", lastCmd)
+	}
+	if mode := pair.Left().RunMode(); mode != MotorRunModeNone {
+		t.Errorf("Expected left run mode None, got %v", mode)
+	}
+}
@@ -1,12 +1,31 @@
 package buildhat
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+func TestMotor_ConfiguresCombiInOneWrite(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.Motor(PortB)
+
+	expected := "port 1 ; combi 0 1 0 2 0 3 0 ; select 0 ; selrate 10\r"
+	if history := brick.GetMockPort().GetWriteHistory(); len(history) == 0 || history[0] != expected {
+		t.Errorf("Expected combi setup %q as the first write, got %q", expected, history)
+	}
+}
+
 func TestMotor_SetDefaultSpeed(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -99,6 +118,48 @@ func TestMotor_RunForRotations(t *testing.T) {
 	}
 }
 
+func TestRotationsToDegrees(t *testing.T) {
+	tests := []struct {
+		rotations float64
+		degrees   int
+	}{
+		{0.25, 90},
+		{1.5, 540},
+		{2.999, 1080}, // 1079.64 rounds up rather than truncating
+		{1.3, 468},
+		{0.999, 360},
+		{-1.5, -540},
+	}
+
+	for _, tt := range tests {
+		if got := rotationsToDegrees(tt.rotations); got != tt.degrees {
+			t.Errorf("rotationsToDegrees(%g) = %d, want %d", tt.rotations, got, tt.degrees)
+		}
+	}
+}
+
+func TestMotor_RunForRotations_RoundsToNearestDegree(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+
+	motor := brick.Motor(PortA)
+	mockPort.ClearWriteHistory()
+
+	if err := motor.RunForRotations(2.999, 50); err != nil {
+		t.Fatalf("RunForRotations failed: %v", err)
+	}
+
+	// 1080 degrees is exactly 3 rotations
+	if !slices.ContainsFunc(mockPort.GetWriteHistory(), func(cmd string) bool {
+		return strings.Contains(cmd, "set ramp 0.000000 3.000000 ")
+	}) {
+		t.Errorf("Expected a ramp to 3 rotations, got %v", mockPort.GetWriteHistory())
+	}
+}
+
 func TestMotor_RunForDegrees(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -151,6 +212,37 @@ func TestMotor_RunForDegrees(t *testing.T) {
 	}
 }
 
+func TestMotor_RunForDegreesContext_Cancel(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	motor := brick.Motor(PortA)
+	mockPort.ClearWriteHistory()
+
+	// The mock reports "ramp done" 50ms after the ramp, so cancel well before that
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := motor.RunForDegreesContext(ctx, 360, 50)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if last := mockPort.GetLastWrite(); last != "port 0 ; coast\r" {
+		t.Errorf("Expected the motor to coast on cancellation, got %q", last)
+	}
+	if motor.runMode != MotorRunModeNone {
+		t.Errorf("Expected run mode to be reset, got %d", motor.runMode)
+	}
+	brick.mu.RLock()
+	pending := len(brick.rampFutures[PortA])
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected the ramp future to be removed, got %d pending", pending)
+	}
+}
+
 func TestMotor_RunForDuration(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -195,27 +287,72 @@ func TestMotor_RunForDuration(t *testing.T) {
 	}
 }
 
-func TestMotor_RunToPosition(t *testing.T) {
+func TestMotor_RunForDuration_RPM(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
 
 	mockPort := brick.GetMockPort()
+	motor := brick.Motor(PortB)
+	motor.SetSpeedUnitRPM(true)
 
-	// Queue motor data: speed=0, position=0, aposition=0
-	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	tests := []struct {
+		rpm      int
+		expected string
+	}{
+		// 60 RPM is one rotation per second
+		{60, "port 1 ; select 0 ; selrate 10 ; pid_diff 1 0 5 s2 0.0027777778 1 0 2.5 0 0.4 0.01 ; set pulse 1.000000 0.0 0.100000 0\r"},
+		{-30, "port 1 ; select 0 ; selrate 10 ; pid_diff 1 0 5 s2 0.0027777778 1 0 2.5 0 0.4 0.01 ; set pulse -0.500000 0.0 0.100000 0\r"},
+		{100, "port 1 ; select 0 ; selrate 10 ; pid_diff 1 0 5 s2 0.0027777778 1 0 2.5 0 0.4 0.01 ; set pulse 1.666667 0.0 0.100000 0\r"},
+	}
+	for _, tt := range tests {
+		mockPort.ClearWriteHistory()
+		if err := motor.RunForDuration(100*time.Millisecond, tt.rpm); err != nil {
+			t.Fatalf("RunForDuration(%d RPM) failed: %v", tt.rpm, err)
+		}
+
+		history := mockPort.GetWriteHistory()
+		if len(history) != 2 || history[0] != tt.expected || history[1] != "port 1 ; coast\r" {
+			t.Errorf("%d RPM: expected [%q coast], got %q", tt.rpm, tt.expected, history)
+		}
+	}
+
+	// The speed range is checked before conversion, as for percent speeds
+	if err := motor.RunForDuration(100*time.Millisecond, 101); err == nil {
+		t.Error("Expected error for 101 RPM")
+	}
+}
+
+func TestMotor_RunForDuration_ResetsRunModeOnError(t *testing.T) {
+	brick := TestBrick(t)
+	motor := brick.Motor(PortA)
+	brick.Close()
+
+	if err := motor.RunForDuration(100*time.Millisecond, 50); !errors.Is(err, ErrBrickClosed) {
+		t.Fatalf("Expected ErrBrickClosed, got %v", err)
+	}
+	if motor.runMode != MotorRunModeNone {
+		t.Errorf("Expected run mode to be reset, got %d", motor.runMode)
+	}
+
+	brick.mu.RLock()
+	defer brick.mu.RUnlock()
+	if n := len(brick.pulseFutures[PortA]); n != 0 {
+		t.Errorf("Expected no pending pulse futures, got %d", n)
+	}
+}
+
+func TestMotor_RunToPosition(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
 
 	motor := brick.Motor(PortA)
 	mockPort.ClearWriteHistory() // Clear initialization commands
 
-	// Queue more sensor data since getData() sends select command and waits for response
-	time.Sleep(20 * time.Millisecond) // Let first data be cached
-	go func() {
-		// Continuously provide sensor data while test runs
-		for i := 0; i < 10; i++ {
-			time.Sleep(50 * time.Millisecond)
-			mockPort.SimulateSensorResponse("0", 0, "0 0 0")
-		}
-	}()
+	// Stream motor data: speed=0, position=0, aposition=0
+	stop := mockPort.SimulateMotorStream("0", [][3]int{{0, 0, 0}}, 50*time.Millisecond)
+	defer stop()
 
 	// Test basic position move: 90 degrees at 50% speed from position 0
 	// Expected calculation:
@@ -267,6 +404,70 @@ func TestMotor_RunToPosition_InvalidDirection(t *testing.T) {
 	}
 }
 
+func TestMotor_RunToPosition_Timeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+	// Writes bypass the mock so no "ramp done" is sent back
+	brick := NewBrick(mockPort, &safeBuffer{}, logger)
+	defer brick.Close()
+
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	motor := brick.Motor(PortA)
+
+	if err := motor.RunToPosition(10, 100, DirectionShortest); err == nil {
+		t.Fatal("Expected a timeout without a ramp done message")
+	}
+	if motor.RunMode() != MotorRunModeNone {
+		t.Errorf("Expected run mode None after a timed out move, got %s", motor.RunMode())
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.rampFutures[PortA])
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected the timed out future to be removed, got %d pending", pending)
+	}
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(data []byte) (int, error) {
+	return f(data)
+}
+
+func TestMotor_RunToPosition_WriteFailsKeepsOtherFutures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+
+	// Another caller registers its future while this move's ramp write is failing
+	var motor *Motor
+	var other chan bool
+	writer := writerFunc(func(data []byte) (int, error) {
+		if strings.Contains(string(data), "set ramp") {
+			other = motor.addRampFuture()
+			return 0, errors.New("write failed")
+		}
+		return mockPort.Write(data)
+	})
+	brick := NewBrick(mockPort, writer, logger)
+	defer brick.Close()
+
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	motor = brick.Motor(PortA)
+
+	if err := motor.RunToPosition(90, 50, DirectionShortest); err == nil {
+		t.Fatal("Expected an error when the ramp cannot be sent")
+	}
+
+	brick.mu.RLock()
+	futures := slices.Clone(brick.rampFutures[PortA])
+	brick.mu.RUnlock()
+	if len(futures) != 1 || futures[0] != other {
+		t.Errorf("Expected only the other caller's future to remain, got %d futures", len(futures))
+	}
+}
+
 func TestMotor_RunToPosition_InvalidAngle(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -418,6 +619,57 @@ func TestMotor_GetAbsolutePosition(t *testing.T) {
 	}
 }
 
+func TestMotor_GetRevolutions(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	for _, tt := range []struct {
+		position int
+		expected float64
+	}{{900, 2.5}, {-180, -0.5}, {0, 0}} {
+		brick.handleSensorData(PortA, fmt.Sprintf("P0C0: 0 %d 0", tt.position))
+		revolutions, err := motor.GetRevolutions()
+		if err != nil {
+			t.Fatalf("GetRevolutions failed: %v", err)
+		}
+		if revolutions != tt.expected {
+			t.Errorf("Position %d: expected %g revolutions, got %g", tt.position, tt.expected, revolutions)
+		}
+	}
+}
+
+func TestMotor_GetContinuousAngle(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	// Forward across the 180 wrap, then backward across it again
+	tests := []struct {
+		position, absolute int
+		expected           int
+	}{
+		{170, 170, 170},
+		{190, -170, 190},
+		{370, 10, 370},
+		{365, 8, 368}, // Tacho drifted a few degrees from the absolute encoder
+		{-190, 170, -190},
+		{-540, 180, -540},
+	}
+	for _, tt := range tests {
+		brick.handleSensorData(PortA, fmt.Sprintf("P0C0: 0 %d %d", tt.position, tt.absolute))
+		angle, err := motor.GetContinuousAngle()
+		if err != nil {
+			t.Fatalf("GetContinuousAngle failed: %v", err)
+		}
+		if angle != tt.expected {
+			t.Errorf("Position %d, absolute %d: expected %d, got %d", tt.position, tt.absolute, tt.expected, angle)
+		}
+	}
+}
+
 func TestMotor_GetSpeed(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -513,6 +765,57 @@ func TestMotor_SetPWMParams(t *testing.T) {
 	}
 }
 
+func TestMotor_ConfigGetters(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	// Initialization values are readable
+	if got := motor.GetPowerLimit(); got != 0.7 {
+		t.Errorf("Expected default power limit 0.7, got %g", got)
+	}
+	if got := motor.GetBias(); got != 0.3 {
+		t.Errorf("Expected default bias 0.3, got %g", got)
+	}
+	if thresh, minPWM := motor.GetPWMParams(); thresh != 0.65 || minPWM != 0.01 {
+		t.Errorf("Expected default PWM params 0.65/0.01, got %g/%g", thresh, minPWM)
+	}
+
+	if err := motor.SetPowerLimit(0.5); err != nil {
+		t.Fatalf("SetPowerLimit failed: %v", err)
+	}
+	if err := motor.SetBias(0.2); err != nil {
+		t.Fatalf("SetBias failed: %v", err)
+	}
+	if err := motor.SetPWMParams(0.7, 0.02); err != nil {
+		t.Fatalf("SetPWMParams failed: %v", err)
+	}
+	if got := motor.GetPowerLimit(); got != 0.5 {
+		t.Errorf("Expected power limit 0.5, got %g", got)
+	}
+	if got := motor.GetBias(); got != 0.2 {
+		t.Errorf("Expected bias 0.2, got %g", got)
+	}
+	if thresh, minPWM := motor.GetPWMParams(); thresh != 0.7 || minPWM != 0.02 {
+		t.Errorf("Expected PWM params 0.7/0.02, got %g/%g", thresh, minPWM)
+	}
+	if history := brick.GetMockPort().GetWriteHistory(); !slices.Contains(history, "port 0 ; bias 0.2\r") {
+		t.Errorf("Expected bias command to be sent, got %v", history)
+	}
+
+	// Rejected values leave the stored configuration untouched
+	_ = motor.SetPowerLimit(1.5)
+	_ = motor.SetBias(-1)
+	_ = motor.SetPWMParams(2, 0)
+	if motor.GetPowerLimit() != 0.5 || motor.GetBias() != 0.2 {
+		t.Errorf("Expected invalid values to be ignored, got limit %g bias %g", motor.GetPowerLimit(), motor.GetBias())
+	}
+	if thresh, _ := motor.GetPWMParams(); thresh != 0.7 {
+		t.Errorf("Expected invalid PWM params to be ignored, got %g", thresh)
+	}
+}
+
 func TestMotor_PWM(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -630,6 +933,314 @@ func TestMotor_CoastAndFloat(t *testing.T) {
 	}
 }
 
+func TestMotor_Coast_AfterClose(t *testing.T) {
+	brick := TestBrick(t)
+	motor := brick.Motor(PortA)
+
+	brick.Close()
+	mockPort := brick.GetMockPort()
+	mockPort.ClearWriteHistory()
+
+	if err := motor.Coast(); !errors.Is(err, ErrBrickClosed) {
+		t.Errorf("Expected ErrBrickClosed, got %v", err)
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 0 {
+		t.Errorf("Expected no commands after Close, got %v", history)
+	}
+}
+
+func TestMotor_RunUntil(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+	if err := motor.SetPollInterval(time.Millisecond); err != nil {
+		t.Fatalf("SetPollInterval failed: %v", err)
+	}
+	mockPort := brick.GetMockPort()
+	mockPort.ClearWriteHistory()
+
+	polls := 0
+	err := motor.RunUntil(context.Background(), 50, func() (bool, error) {
+		polls++
+		return polls == 3, nil
+	})
+	if err != nil {
+		t.Fatalf("RunUntil failed: %v", err)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls, got %d", polls)
+	}
+
+	history := mockPort.GetWriteHistory()
+	if len(history) != 2 || !strings.Contains(history[0], "set 50.000000") || history[1] != "port 0 ; coast\r" {
+		t.Errorf("Expected a start followed by a coast, got %v", history)
+	}
+	if motor.runMode != MotorRunModeNone {
+		t.Errorf("Expected motor to be stopped, got run mode %d", motor.runMode)
+	}
+}
+
+func TestMotor_RunUntil_StopsOnErrorAndCancel(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortB)
+	motor.SetPollInterval(time.Millisecond)
+	mockPort := brick.GetMockPort()
+
+	sensorErr := errors.New("sensor unplugged")
+	mockPort.ClearWriteHistory()
+	err := motor.RunUntil(context.Background(), 30, func() (bool, error) { return false, sensorErr })
+	if !errors.Is(err, sensorErr) {
+		t.Errorf("Expected the predicate error, got %v", err)
+	}
+	if last := mockPort.GetLastWrite(); last != "port 1 ; coast\r" {
+		t.Errorf("Expected coast after a predicate error, got %q", last)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mockPort.ClearWriteHistory()
+	err = motor.RunUntil(ctx, 30, func() (bool, error) { return false, nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if last := mockPort.GetLastWrite(); last != "port 1 ; coast\r" {
+		t.Errorf("Expected coast after cancellation, got %q", last)
+	}
+
+	if err := motor.SetPollInterval(0); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+}
+
+func TestMotor_SetRampProfile(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+	mockPort := brick.GetMockPort()
+
+	// Default profile keeps the stock constants
+	mockPort.ClearWriteHistory()
+	if err := motor.holdAt(0.25); err != nil {
+		t.Fatalf("holdAt failed: %v", err)
+	}
+	if last := mockPort.GetLastWrite(); !strings.Contains(last, "pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ;") {
+		t.Errorf("Expected default position PID, got %q", last)
+	}
+
+	if err := motor.SetRampProfile(2, 0.5); err != nil {
+		t.Fatalf("SetRampProfile failed: %v", err)
+	}
+	mockPort.ClearWriteHistory()
+	if err := motor.holdAt(0.25); err != nil {
+		t.Fatalf("holdAt failed: %v", err)
+	}
+	if last := mockPort.GetLastWrite(); !strings.Contains(last, "pid 0 0 1 s4 0.0027777778 0 10 0 0.05 3 0.01 ;") {
+		t.Errorf("Expected scaled position PID, got %q", last)
+	}
+
+	for _, profile := range [][2]float64{{0, 1}, {1, 0}, {-1, 1}} {
+		if err := motor.SetRampProfile(profile[0], profile[1]); err == nil {
+			t.Errorf("Expected error for profile %v", profile)
+		}
+	}
+}
+
+func TestMotor_SetPositionPID(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+	mockPort := brick.GetMockPort()
+
+	if err := motor.SetPositionPID(8, 0.5, 0.2, 4, 0.02); err != nil {
+		t.Fatalf("SetPositionPID failed: %v", err)
+	}
+	mockPort.ClearWriteHistory()
+	if err := motor.holdAt(0.5); err != nil {
+		t.Fatalf("holdAt failed: %v", err)
+	}
+	expected := "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 8 0.5 0.2 4 0.02 ; set 0.500000\r"
+	if last := mockPort.GetLastWrite(); last != expected {
+		t.Errorf("Expected exact command %q, got %q", expected, last)
+	}
+
+	// The ramp profile scales the configured gains
+	motor.SetRampProfile(0.5, 2)
+	mockPort.ClearWriteHistory()
+	motor.holdAt(0.5)
+	if last := mockPort.GetLastWrite(); !strings.Contains(last, "pid 0 0 1 s4 0.0027777778 0 4 0.5 0.4 4 0.02 ;") {
+		t.Errorf("Expected profile-scaled gains, got %q", last)
+	}
+
+	if err := motor.SetPositionPID(-1, 0, 0, 3, 0.01); err == nil {
+		t.Error("Expected error for a negative gain")
+	}
+}
+
+func TestMotor_SetSpeedPID(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortB)
+	mockPort := brick.GetMockPort()
+
+	if err := motor.SetSpeedPID(0.005, 0.02, 0.001, 50, 0); err != nil {
+		t.Fatalf("SetSpeedPID failed: %v", err)
+	}
+	mockPort.ClearWriteHistory()
+	if err := motor.Start(40); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	expected := "port 1 ; select 0 ; selrate 10 ; pid 1 0 0 s1 1 0 0.005 0.02 0.001 50 0 ; set 40.000000\r"
+	if last := mockPort.GetLastWrite(); last != expected {
+		t.Errorf("Expected exact command %q, got %q", expected, last)
+	}
+	motor.Stop()
+
+	// RPM units keep their own gains
+	motor.SetSpeedUnitRPM(true)
+	mockPort.ClearWriteHistory()
+	motor.Start(60)
+	if last := mockPort.GetLastWrite(); !strings.Contains(last, "pid_diff 1 0 5 s2 0.0027777778 1 0 2.5 0 0.4 0.01 ;") {
+		t.Errorf("Expected default RPM gains, got %q", last)
+	}
+	motor.Stop()
+
+	if err := motor.SetSpeedPID(0.1, 3, 0, 0.5, 0.01); err != nil {
+		t.Fatalf("SetSpeedPID failed: %v", err)
+	}
+	mockPort.ClearWriteHistory()
+	motor.Start(60)
+	if last := mockPort.GetLastWrite(); !strings.Contains(last, "pid_diff 1 0 5 s2 0.0027777778 1 0.1 3 0 0.5 0.01 ;") {
+		t.Errorf("Expected custom RPM gains, got %q", last)
+	}
+}
+
+func TestMotor_RunMode(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+	if motor.IsMoving() || motor.RunMode() != MotorRunModeNone {
+		t.Fatalf("Expected a new motor to be idle, got %s", motor.RunMode())
+	}
+
+	if err := motor.Start(40); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !motor.IsMoving() || motor.RunMode() != MotorRunModeFree {
+		t.Errorf("Expected Free after Start, got %s", motor.RunMode())
+	}
+
+	if err := motor.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if motor.IsMoving() || motor.RunMode() != MotorRunModeNone {
+		t.Errorf("Expected None after Stop, got %s", motor.RunMode())
+	}
+
+	// Observed from another goroutine while a timed run is in progress
+	done := make(chan error, 1)
+	go func() { done <- motor.RunForDuration(100*time.Millisecond, 40) }()
+	deadline := time.Now().Add(time.Second)
+	for motor.RunMode() != MotorRunModeSeconds && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if motor.RunMode() != MotorRunModeSeconds {
+		t.Errorf("Expected Seconds during RunForDuration, got %s", motor.RunMode())
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("RunForDuration failed: %v", err)
+	}
+	if motor.IsMoving() {
+		t.Errorf("Expected None after RunForDuration, got %s", motor.RunMode())
+	}
+}
+
+func TestMotor_WaitUntilStopped(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+	motor := brick.Motor(PortA)
+	motor.SetReleaseDelay(0)
+
+	// Idle motors return at once
+	if err := motor.WaitUntilStopped(context.Background()); err != nil {
+		t.Fatalf("WaitUntilStopped on an idle motor failed: %v", err)
+	}
+
+	mockPort.ClearWriteHistory()
+	if err := motor.RunForDegreesAsync(90, 50); err != nil {
+		t.Fatalf("RunForDegreesAsync failed: %v", err)
+	}
+	if !motor.IsMoving() {
+		t.Error("Expected the motor to be moving right after RunForDegreesAsync")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := motor.WaitUntilStopped(ctx); err != nil {
+		t.Fatalf("WaitUntilStopped failed: %v", err)
+	}
+	if motor.IsMoving() {
+		t.Errorf("Expected None after WaitUntilStopped, got %s", motor.RunMode())
+	}
+	if last := mockPort.GetLastWrite(); last != "port 0 ; coast\r" {
+		t.Errorf("Expected the move to finish with a coast, got %q", last)
+	}
+}
+
+func TestMotor_WaitUntilStopped_Context(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortB)
+	if err := motor.Start(30); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// A free-running motor never stops on its own
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := motor.WaitUntilStopped(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Stop releases waiters
+	waited := make(chan error, 1)
+	go func() { waited <- motor.WaitUntilStopped(context.Background()) }()
+	time.Sleep(5 * time.Millisecond)
+	motor.Stop()
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("WaitUntilStopped failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitUntilStopped did not return after Stop")
+	}
+}
+
+func TestMotorRunMode_String(t *testing.T) {
+	for mode, want := range map[MotorRunMode]string{
+		MotorRunModeNone:    "None",
+		MotorRunModeFree:    "Free",
+		MotorRunModeDegrees: "Degrees",
+		MotorRunModeSeconds: "Seconds",
+		MotorRunMode(9):     "MotorRunMode(9)",
+	} {
+		if got := mode.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
 func TestMotor_SetRelease(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -651,3 +1262,474 @@ func TestMotor_SetRelease(t *testing.T) {
 		t.Error("Expected release to be true")
 	}
 }
+
+func TestMotor_SetReleaseDelay(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	if motor.releaseDelay != 200*time.Millisecond {
+		t.Errorf("Expected default release delay 200ms, got %v", motor.releaseDelay)
+	}
+
+	if err := motor.SetReleaseDelay(-time.Millisecond); err == nil {
+		t.Error("Expected error for negative release delay")
+	}
+
+	if err := motor.SetReleaseDelay(50 * time.Millisecond); err != nil {
+		t.Fatalf("SetReleaseDelay failed: %v", err)
+	}
+	if motor.releaseDelay != 50*time.Millisecond {
+		t.Errorf("Expected release delay 50ms, got %v", motor.releaseDelay)
+	}
+}
+
+func TestMotor_RunForDegrees_ReleaseDelayHonored(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		minElapsed time.Duration
+		maxElapsed time.Duration
+	}{
+		{"zero delay coasts immediately", 0, 0, 400 * time.Millisecond},
+		{"long delay waits before coasting", 600 * time.Millisecond, 600 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brick := TestBrick(t)
+			defer CleanupTestBrick(brick)
+
+			mockPort := brick.GetMockPort()
+			mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+
+			motor := brick.Motor(PortA)
+			if err := motor.SetReleaseDelay(tt.delay); err != nil {
+				t.Fatalf("SetReleaseDelay failed: %v", err)
+			}
+			mockPort.ClearWriteHistory()
+
+			start := time.Now()
+			if err := motor.RunForDegrees(10, 100); err != nil {
+				t.Fatalf("RunForDegrees failed: %v", err)
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Errorf("Expected RunForDegrees to take between %v and %v, took %v", tt.minElapsed, tt.maxElapsed, elapsed)
+			}
+			if last := mockPort.GetLastWrite(); last != "port 0 ; coast\r" {
+				t.Errorf("Expected final command to be coast, got %q", last)
+			}
+		})
+	}
+}
+
+func TestMotor_Hold(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 90 90")
+
+	motor := brick.Motor(PortA)
+	mockPort.ClearWriteHistory()
+
+	if err := motor.Hold(); err != nil {
+		t.Fatalf("Hold failed: %v", err)
+	}
+
+	expected := "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set 0.250000\r"
+	if last := mockPort.GetLastWrite(); last != expected {
+		t.Errorf("Expected exact command %q, got %q", expected, last)
+	}
+}
+
+func TestMotor_RunForDegrees_HoldsWhenReleaseDisabled(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+
+	motor := brick.Motor(PortA)
+	motor.SetRelease(false)
+	mockPort.ClearWriteHistory()
+
+	if err := motor.RunForDegrees(360, 50); err != nil {
+		t.Fatalf("RunForDegrees failed: %v", err)
+	}
+
+	expected := "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set 1.000000\r"
+	last := mockPort.GetLastWrite()
+	if last != expected {
+		t.Errorf("Expected final command to hold with %q, got %q", expected, last)
+	}
+	if strings.Contains(last, "coast") {
+		t.Error("Expected no coast when release is disabled")
+	}
+}
+
+func TestMotor_RunToPosition_HoldsWhenReleaseDisabled(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("0", 0, "0 0 0")
+
+	motor := brick.Motor(PortA)
+	motor.SetRelease(false)
+	mockPort.ClearWriteHistory()
+
+	if err := motor.RunToPosition(90, 50, DirectionShortest); err != nil {
+		t.Fatalf("RunToPosition failed: %v", err)
+	}
+
+	expected := "port 0 ; select 0 ; selrate 10 ; pid 0 0 1 s4 0.0027777778 0 5 0 0.1 3 0.01 ; set 0.250000\r"
+	if last := mockPort.GetLastWrite(); last != expected {
+		t.Errorf("Expected final command to hold with %q, got %q", expected, last)
+	}
+}
+
+func TestMotor_getCurrentAndAbsolutePosition_RetriesShortSample(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	// A short sample right after configuration, then a full one
+	brick.handleSensorData(PortA, "P0M0: 0 12")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		brick.handleSensorData(PortA, "P0M0: 0 90 45")
+	}()
+
+	pos, apos, err := motor.getCurrentAndAbsolutePosition()
+	if err != nil {
+		t.Fatalf("Expected the short sample to be retried, got error: %v", err)
+	}
+	if pos != 90 || apos != 45 {
+		t.Errorf("Expected position 90/45 from the full sample, got %d/%d", pos, apos)
+	}
+
+	// Without retries the short sample fails straight away
+	if err := motor.SetPartialDataRetries(0, time.Second); err != nil {
+		t.Fatalf("SetPartialDataRetries failed: %v", err)
+	}
+	brick.handleSensorData(PortA, "P0M0: 0 12")
+	if _, _, err := motor.getCurrentAndAbsolutePosition(); err == nil {
+		t.Error("Expected an error for a short sample without retries")
+	}
+
+	if err := motor.SetPartialDataRetries(-1, time.Second); err == nil {
+		t.Error("Expected error for negative retries")
+	}
+}
+
+func TestMotor_OnData_StopOneOfTwo(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	// Two closures built from the same function literal share a code pointer
+	collect := func(samples chan MotorData) func(MotorData) {
+		return func(d MotorData) { samples <- d }
+	}
+	first := make(chan MotorData, 4)
+	second := make(chan MotorData, 4)
+	stopFirst := motor.OnData(collect(first))
+	stopSecond := motor.OnData(collect(second))
+	defer stopSecond()
+
+	stopFirst()
+	brick.handleSensorData(PortA, "P0C0: 10 100 90")
+
+	select {
+	case got := <-second:
+		if got.Speed != 10 {
+			t.Errorf("Expected speed 10, got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the remaining handler to keep receiving samples")
+	}
+	select {
+	case got := <-first:
+		t.Errorf("Expected no samples for the stopped handler, got %+v", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestMotor_OnData(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	samples := make(chan MotorData, 4)
+	stop := motor.OnData(func(d MotorData) { samples <- d })
+	defer stop()
+
+	brick.handleSensorData(PortA, "P0C0: 10 100 90")
+	brick.handleSensorData(PortA, "P0C0: 20 200 -170")
+	brick.handleSensorData(PortA, "P0C0: 5")       // Too short, skipped
+	brick.handleSensorData(PortA, "P0C0: -30 150") // No absolute position
+	brick.handleSensorData(PortB, "P1C0: 1 2 3")   // Other port
+
+	expected := []MotorData{
+		{Speed: 10, Position: 100, AbsolutePosition: 90, HasAbsolute: true},
+		{Speed: 20, Position: 200, AbsolutePosition: -170, HasAbsolute: true},
+		{Speed: -30, Position: 150},
+	}
+	for i, want := range expected {
+		select {
+		case got := <-samples:
+			if got != want {
+				t.Errorf("Sample %d: expected %+v, got %+v", i, want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for sample %d", i)
+		}
+	}
+
+	// No more samples once stopped
+	stop()
+	brick.handleSensorData(PortA, "P0C0: 1 1 1")
+	select {
+	case got := <-samples:
+		t.Errorf("Expected no sample after stop, got %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMotor_SetPositionTo(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	motor := brick.Motor(PortA)
+	mockPort.ClearWriteHistory()
+
+	if err := motor.SetPositionTo(90); err != nil {
+		t.Fatalf("SetPositionTo failed: %v", err)
+	}
+	if history := mockPort.GetWriteHistory(); len(history) != 1 || history[0] != "port 0 ; preset\r" {
+		t.Errorf("Expected a single preset command, got %v", history)
+	}
+
+	// The HAT counts from 0 after the preset; the offset is added on top
+	brick.handleSensorData(PortA, "P0C0: 0 10 45")
+	if pos, err := motor.GetPosition(); err != nil || pos != 100 {
+		t.Errorf("Expected position 100, got %d (%v)", pos, err)
+	}
+
+	// Setpoints stay in the HAT's frame: a relative move ramps from the counter, not from 90
+	mockPort.ClearWriteHistory()
+	brick.handleSensorData(PortA, "P0C0: 0 0 45")
+	if err := motor.RunForDegrees(360, 50); err != nil {
+		t.Fatalf("RunForDegrees failed: %v", err)
+	}
+	ramp := ""
+	for _, cmd := range mockPort.GetWriteHistory() {
+		if strings.Contains(cmd, "set ramp") {
+			ramp = cmd
+			break
+		}
+	}
+	if !strings.Contains(ramp, "set ramp 0.000000 1.000000 ") {
+		t.Errorf("Expected a ramp from 0 to 1 rotation after SetPositionTo, got %q", ramp)
+	}
+
+	brick.handleSensorData(PortA, "P0C0: 0 90 45")
+	if err := motor.Hold(); err != nil {
+		t.Fatalf("Hold failed: %v", err)
+	}
+	if last := mockPort.GetLastWrite(); !strings.HasSuffix(last, "; set 0.250000\r") {
+		t.Errorf("Expected Hold at the HAT's 90 degrees (0.25), got %q", last)
+	}
+
+	// MoveMotorToPosition targets the SetPositionTo frame: 190 is 100 on the HAT's counter
+	mockPort.ClearWriteHistory()
+	stopStream := mockPort.SimulateMotorStream("A", [][3]int{{0, 10, 45}}, 5*time.Millisecond)
+	err := brick.MoveMotorToPosition(PortA, 190, 50)
+	stopStream()
+	if err != nil {
+		t.Fatalf("MoveMotorToPosition failed: %v", err)
+	}
+	if history := strings.Join(mockPort.GetWriteHistory(), ""); !strings.Contains(history, "set ramp 0.027778 0.277778 ") {
+		t.Errorf("Expected a ramp from 10 to 100 degrees, got %q", history)
+	}
+
+	// Presetting to 0 drops the offset
+	if err := motor.PresetPosition(); err != nil {
+		t.Fatalf("PresetPosition failed: %v", err)
+	}
+	brick.handleSensorData(PortA, "P0C0: 0 10 45")
+	if pos, err := motor.GetPosition(); err != nil || pos != 10 {
+		t.Errorf("Expected position 10 after preset, got %d (%v)", pos, err)
+	}
+}
+
+func TestMotor_RampSpeed(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	motor := brick.Motor(PortA)
+
+	if err := motor.Start(50); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mockPort.ClearWriteHistory()
+
+	if err := motor.RampSpeed(-20, 300*time.Millisecond); err != nil {
+		t.Fatalf("RampSpeed failed: %v", err)
+	}
+
+	// The ramp runs on the speed controller, from the current speed to the target
+	expected := "port 0 ; select 0 ; selrate 10 ; pid 0 0 0 s1 1 0 0.003 0.01 0 100 0.01 ; set ramp 50.000000 -20.000000 0.300000 0\r"
+	if history := mockPort.GetWriteHistory(); len(history) != 1 || history[0] != expected {
+		t.Errorf("Expected exact ramp command %q, got %q", expected, history)
+	}
+	if motor.RunMode() != MotorRunModeFree {
+		t.Errorf("Expected motor to keep running freely, got %s", motor.RunMode())
+	}
+
+	// The next ramp starts from the reached speed
+	mockPort.ClearWriteHistory()
+	if err := motor.RampSpeed(0, 100*time.Millisecond); err != nil {
+		t.Fatalf("RampSpeed failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); !strings.HasSuffix(lastCmd, "; set ramp -20.000000 0.000000 0.100000 0\r") {
+		t.Errorf("Expected ramp from -20 to 0, got %q", lastCmd)
+	}
+
+	if err := motor.RampSpeed(101, time.Second); err == nil {
+		t.Error("Expected error for out of range speed")
+	}
+	if err := motor.RampSpeed(50, 0); err == nil {
+		t.Error("Expected error for zero duration")
+	}
+}
+
+func TestMotor_RampSpeed_Stopped(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+	// Writes bypass the mock so no "ramp done" is sent back
+	output := &safeBuffer{}
+	brick := NewBrick(mockPort, output, logger)
+	defer brick.Close()
+
+	motor := brick.Motor(PortA)
+	if err := motor.Start(50); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = brick.StopAll()
+	}()
+	if err := motor.RampSpeed(20, time.Second); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+	if motor.RunMode() != MotorRunModeNone {
+		t.Errorf("Expected run mode None after an interrupted ramp, got %s", motor.RunMode())
+	}
+
+	// The next ramp must not start from the speed the interrupted one was heading for
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = brick.StopAll()
+	}()
+	_ = motor.RampSpeed(30, time.Second)
+	if !strings.Contains(output.String(), "; set ramp 0.000000 30.000000 1.000000 0\r") {
+		t.Errorf("Expected the next ramp to start from 0, got %q", output.String())
+	}
+}
+
+func TestMotor_RampSpeed_Timeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockPort := NewMockSerialPort(logger)
+	// Writes bypass the mock so no "ramp done" is sent back
+	brick := NewBrick(mockPort, &safeBuffer{}, logger)
+	defer brick.Close()
+
+	motor := brick.Motor(PortA)
+	if err := motor.RampSpeed(20, 10*time.Millisecond); err == nil {
+		t.Fatal("Expected a timeout without a ramp done message")
+	}
+	if motor.RunMode() != MotorRunModeNone {
+		t.Errorf("Expected run mode None after a timed out ramp, got %s", motor.RunMode())
+	}
+
+	brick.mu.RLock()
+	pending := len(brick.rampFutures[PortA])
+	brick.mu.RUnlock()
+	if pending != 0 {
+		t.Errorf("Expected no pending ramp futures, got %d", pending)
+	}
+}
+
+func TestMotor_StreamCSV(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.Motor(PortA)
+
+	var buf safeBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- motor.StreamCSV(ctx, &buf) }()
+
+	// Wait for the header so the subscription is in place
+	for !strings.Contains(buf.String(), "\n") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	brick.handleSensorData(PortA, "P0C0: 10 100 90")
+	brick.handleSensorData(PortA, "P0C0: -5 80")
+	brick.handleSensorData(PortA, "P0C0: 3") // Too short, skipped
+	for strings.Count(buf.String(), "\n") < 3 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %q", lines)
+	}
+	if lines[0] != "timestamp,speed,position,absPosition" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	for i, want := range []string{",10,100,90", ",-5,80,"} {
+		stamp, rest, _ := strings.Cut(lines[i+1], ",")
+		if _, err := time.Parse(time.RFC3339Nano, stamp); err != nil {
+			t.Errorf("Row %d: invalid timestamp %q: %v", i, stamp, err)
+		}
+		if ","+rest != want {
+			t.Errorf("Row %d: expected %q after the timestamp, got %q", i, want, ","+rest)
+		}
+	}
+}
+
+// safeBuffer is a bytes.Buffer safe for one writer and concurrent readers
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -6,10 +6,20 @@ import (
 
 // PassiveMotor creates a passive motor interface for the specified port
 func (b *Brick) PassiveMotor(port Port) *PassiveMotor {
-	return &PassiveMotor{
+	return cachedDevice(b, port, func() *PassiveMotor { return b.newPassiveMotor(port) })
+}
+
+// newPassiveMotor creates and initializes a PassiveMotor interface
+func (b *Brick) newPassiveMotor(port Port) *PassiveMotor {
+	motor := &PassiveMotor{
 		brick: b,
 		port:  port,
 	}
+
+	// Passive motors are driven directly, without a drive offset
+	_ = b.SetMotorBias(port, 0)
+
+	return motor
 }
 
 // PassiveMotor provides a Python-like passive motor interface (WeDo motors)
@@ -98,6 +98,34 @@ func TestPassiveMotor_SetSpeed(t *testing.T) {
 	}
 }
 
+func TestPassiveMotor_SetSpeed_UsesSetConstant(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	motor := brick.PassiveMotor(PortB)
+	mockPort := brick.GetMockPort()
+
+	for _, speed := range []int{50, -50, 1, -100} {
+		if err := motor.SetSpeed(speed); err != nil {
+			t.Fatalf("SetSpeed(%d) failed: %v", speed, err)
+		}
+
+		// Speed must go through the shared setpoint formatting
+		expectedCmd := Compound(SelectPort(PortB), SetConstant(float64(speed))).CommandString() + "\r"
+		if lastCmd := mockPort.GetLastWrite(); lastCmd != expectedCmd {
+			t.Errorf("Expected exact command '%s', got: %s", expectedCmd, lastCmd)
+		}
+	}
+
+	// And keep the integer form the firmware has always received
+	if err := motor.SetSpeed(50); err != nil {
+		t.Fatalf("SetSpeed(50) failed: %v", err)
+	}
+	if lastCmd := mockPort.GetLastWrite(); lastCmd != "port 1 ; set 50\r" {
+		t.Errorf("Expected exact command 'port 1 ; set 50\\r', got: %s", lastCmd)
+	}
+}
+
 func TestPassiveMotor_AllPorts(t *testing.T) {
 	brick := TestBrick(t)
 	defer CleanupTestBrick(brick)
@@ -0,0 +1,40 @@
+package buildhat
+
+import (
+	"fmt"
+)
+
+// PassiveSensor creates an interface for a passive device the library doesn't recognise
+func (b *Brick) PassiveSensor(port Port) *PassiveSensor {
+	return cachedDevice(b, port, func() *PassiveSensor {
+		return &PassiveSensor{
+			brick: b,
+			port:  port,
+		}
+	})
+}
+
+// PassiveSensor drives the output of an unrecognised passive device, such as a third-party
+// actuator. Passive devices have no modes to read, so only the port power can be set.
+type PassiveSensor struct {
+	brick *Brick
+	port  Port
+}
+
+// SetOutput sets the PWM output of the port (-1 to 1)
+func (s *PassiveSensor) SetOutput(value float64) error {
+	if value < -1 || value > 1 {
+		return fmt.Errorf("output must be between -1 and 1, got %g", value)
+	}
+	return s.brick.writeCommand(Compound(SelectPort(s.port), PWM(), SetConstant(value)))
+}
+
+// On sets the output to full power
+func (s *PassiveSensor) On() error {
+	return s.brick.writeCommand(Compound(SelectPort(s.port), On()))
+}
+
+// Off sets the output to zero
+func (s *PassiveSensor) Off() error {
+	return s.brick.writeCommand(Compound(SelectPort(s.port), Off()))
+}
@@ -0,0 +1,46 @@
+package buildhat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassiveSensor_Commands(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	sensor := brick.PassiveSensor(PortC)
+
+	tests := []struct {
+		name     string
+		run      func() error
+		expected string
+	}{
+		{"set output", func() error { return sensor.SetOutput(0.5) }, "port 2 ; pwm ; set 0.5\r"},
+		{"set negative output", func() error { return sensor.SetOutput(-1) }, "port 2 ; pwm ; set -1\r"},
+		{"on", sensor.On, "port 2 ; on\r"},
+		{"off", sensor.Off, "port 2 ; off\r"},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); err != nil {
+			t.Fatalf("%s failed: %v", tt.name, err)
+		}
+		if last := mockPort.GetLastWrite(); last != tt.expected {
+			t.Errorf("%s: expected exact command %q, got %q", tt.name, tt.expected, last)
+		}
+	}
+}
+
+func TestPassiveSensor_SetOutput_Invalid(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	sensor := brick.PassiveSensor(PortA)
+	for _, value := range []float64{-1.5, 1.01, 2} {
+		err := sensor.SetOutput(value)
+		if err == nil || !strings.Contains(err.Error(), "between -1 and 1") {
+			t.Errorf("SetOutput(%g): expected range error, got %v", value, err)
+		}
+	}
+}
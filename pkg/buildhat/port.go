@@ -2,7 +2,9 @@ package buildhat
 
 import "fmt"
 
-// Port represents a physical port on the BuildHat (A, B, C, or D)
+// Port represents a physical port on the BuildHat (A, B, C, or D).
+// Every device constructor, e.g. Brick.Motor or Brick.ColorSensor, takes a Port;
+// convert user input such as "A" or "0" with ParsePort.
 type Port int
 
 const (
@@ -31,6 +33,22 @@ func (p Port) String() string {
 	}
 }
 
+// MarshalText encodes the port as its letter, e.g. in JSON
+func (p Port) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a port from anything ParsePort accepts, so both the letter written
+// by MarshalText and the older numeric encoding are read back
+func (p *Port) UnmarshalText(text []byte) error {
+	port, err := ParsePort(string(text))
+	if err != nil {
+		return err
+	}
+	*p = port
+	return nil
+}
+
 // Int returns the integer value of the port (0-3)
 func (p Port) Int() int {
 	return int(p)
@@ -41,19 +59,19 @@ func (p Port) IsValid() bool {
 	return p >= PortA && p <= PortD
 }
 
-// ParsePort converts a string ("A", "B", "C", or "D") to a BuildHatPort
+// ParsePort converts a port letter ("A"-"D", either case) or number ("0"-"3") to a Port
 func ParsePort(s string) (Port, error) {
 	switch s {
-	case "A":
+	case "A", "a", "0":
 		return PortA, nil
-	case "B":
+	case "B", "b", "1":
 		return PortB, nil
-	case "C":
+	case "C", "c", "2":
 		return PortC, nil
-	case "D":
+	case "D", "d", "3":
 		return PortD, nil
 	default:
-		return -1, fmt.Errorf("invalid port: %s (must be A, B, C, or D)", s)
+		return -1, fmt.Errorf("invalid port: %q (must be A-D or 0-3)", s)
 	}
 }
 
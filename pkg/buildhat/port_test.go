@@ -74,11 +74,22 @@ func TestParsePort(t *testing.T) {
 		{"B", PortB, false},
 		{"C", PortC, false},
 		{"D", PortD, false},
-		{"a", Port(-1), true},
+		{"a", PortA, false},
+		{"b", PortB, false},
+		{"c", PortC, false},
+		{"d", PortD, false},
+		{"0", PortA, false},
+		{"1", PortB, false},
+		{"2", PortC, false},
+		{"3", PortD, false},
 		{"E", Port(-1), true},
+		{"e", Port(-1), true},
+		{"4", Port(-1), true},
+		{"-1", Port(-1), true},
 		{"", Port(-1), true},
 		{"AB", Port(-1), true},
-		{"1", Port(-1), true},
+		{" A", Port(-1), true},
+		{"port A", Port(-1), true},
 	}
 
 	for _, tt := range tests {
@@ -160,3 +171,28 @@ func TestParsePortNumber(t *testing.T) {
 		}
 	}
 }
+
+func TestPort_TextRoundTrip(t *testing.T) {
+	for _, port := range AllPorts() {
+		text, err := port.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) failed: %v", port, err)
+		}
+		var decoded Port
+		if err := decoded.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) failed: %v", text, err)
+		}
+		if decoded != port {
+			t.Errorf("Expected %v after a round trip, got %v", port, decoded)
+		}
+	}
+
+	// The numeric encoding used before ports were written as letters is still accepted
+	var decoded Port
+	if err := decoded.UnmarshalText([]byte("2")); err != nil || decoded != PortC {
+		t.Errorf("Expected \"2\" to decode to port C, got %v (%v)", decoded, err)
+	}
+	if err := decoded.UnmarshalText([]byte("E")); err == nil {
+		t.Error("Expected error for invalid port E")
+	}
+}
@@ -0,0 +1,221 @@
+package buildhat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ReplaySerialPort plays back the lines received in a trace recorded with Brick.SetTracer,
+// keeping their original timing, so a session captured in the field can be reproduced
+// offline. Written commands are recorded but don't affect the playback.
+type ReplaySerialPort struct {
+	mu           sync.Mutex
+	lines        []replayLine
+	next         int       // index of the next line to play
+	started      time.Time // time of the first Read, zero until then
+	pending      []byte    // rest of a line only partly read
+	writeHistory []string
+	closed       bool
+	closing      chan struct{} // closed by Close to wake a waiting Read
+	done         chan struct{} // closed once every line has been read
+}
+
+// replayLine is a received line and when it arrived, relative to the first one
+type replayLine struct {
+	at   time.Duration
+	line string
+}
+
+// traceTimeLayout is the timestamp format written by the tracer
+const traceTimeLayout = "15:04:05.000000"
+
+// NewReplaySerialPort reads a trace in the Brick.SetTracer format ("15:04:05.000000 RX< line").
+// Only received (RX<) lines are played back; sent (TX>) lines and blank lines are skipped.
+func NewReplaySerialPort(trace io.Reader) (*ReplaySerialPort, error) {
+	p := &ReplaySerialPort{
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	var first, prev time.Time
+	var wrap time.Duration // added after the trace crosses midnight
+	scanner := bufio.NewScanner(trace)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		stamp, rest, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid trace line %d: %q", n, text)
+		}
+		at, err := time.Parse(traceTimeLayout, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp on trace line %d: %w", n, err)
+		}
+		direction, line, _ := strings.Cut(rest, " ")
+		switch direction {
+		case "TX>":
+			continue
+		case "RX<":
+		default:
+			return nil, fmt.Errorf("invalid direction %q on trace line %d", direction, n)
+		}
+
+		if first.IsZero() {
+			first = at
+		} else if at.Before(prev) {
+			wrap += 24 * time.Hour
+		}
+		prev = at
+		p.lines = append(p.lines, replayLine{at: at.Sub(first) + wrap, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+
+	if len(p.lines) == 0 {
+		close(p.done)
+	}
+	return p, nil
+}
+
+// Done returns a channel closed once every recorded line has been read
+func (p *ReplaySerialPort) Done() <-chan struct{} {
+	return p.done
+}
+
+// Read implements io.Reader. It blocks until the next recorded line is due, and once the
+// trace is exhausted until the port is closed.
+func (p *ReplaySerialPort) Read(data []byte) (int, error) {
+	p.mu.Lock()
+	if p.started.IsZero() {
+		p.started = time.Now()
+	}
+
+	for len(p.pending) == 0 {
+		if p.closed {
+			p.mu.Unlock()
+			return 0, fmt.Errorf("port is closed")
+		}
+
+		var wait <-chan time.Time
+		if p.next < len(p.lines) {
+			due := p.started.Add(p.lines[p.next].at)
+			if !time.Now().Before(due) {
+				p.pending = []byte(p.lines[p.next].line + "\r\n")
+				p.next++
+				break
+			}
+			wait = time.After(time.Until(due))
+		}
+
+		p.mu.Unlock()
+		select {
+		case <-wait:
+		case <-p.closing:
+		}
+		p.mu.Lock()
+	}
+
+	n := copy(data, p.pending)
+	p.pending = p.pending[n:]
+	if len(p.pending) == 0 && p.next == len(p.lines) {
+		select {
+		case <-p.done:
+		default:
+			close(p.done)
+		}
+	}
+	p.mu.Unlock()
+	return n, nil
+}
+
+// Write implements io.Writer, recording the data
+func (p *ReplaySerialPort) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0, fmt.Errorf("port is closed")
+	}
+	p.writeHistory = append(p.writeHistory, string(data))
+	return len(data), nil
+}
+
+// GetWriteHistory returns all data written to the port
+func (p *ReplaySerialPort) GetWriteHistory() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string{}, p.writeHistory...)
+}
+
+// Close implements io.Closer
+func (p *ReplaySerialPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+	return nil
+}
+
+// SetReadTimeout is a no-op: playback follows the recorded timing
+func (p *ReplaySerialPort) SetReadTimeout(_ time.Duration) error {
+	return nil
+}
+
+// SetWriteTimeout is a no-op
+func (p *ReplaySerialPort) SetWriteTimeout(_ time.Duration) error {
+	return nil
+}
+
+// Break is a no-op
+func (p *ReplaySerialPort) Break(_ time.Duration) error {
+	return nil
+}
+
+// Drain is a no-op
+func (p *ReplaySerialPort) Drain() error {
+	return nil
+}
+
+// ResetInputBuffer is a no-op
+func (p *ReplaySerialPort) ResetInputBuffer() error {
+	return nil
+}
+
+// ResetOutputBuffer is a no-op
+func (p *ReplaySerialPort) ResetOutputBuffer() error {
+	return nil
+}
+
+// SetDTR is a no-op
+func (p *ReplaySerialPort) SetDTR(_ bool) error {
+	return nil
+}
+
+// SetRTS is a no-op
+func (p *ReplaySerialPort) SetRTS(_ bool) error {
+	return nil
+}
+
+// GetModemStatusBits returns no modem status bits
+func (p *ReplaySerialPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return nil, nil
+}
+
+// SetMode is a no-op
+func (p *ReplaySerialPort) SetMode(_ *serial.Mode) error {
+	return nil
+}
@@ -0,0 +1,118 @@
+package buildhat
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+var _ serial.Port = (*ReplaySerialPort)(nil)
+
+func newReplayBrick(t *testing.T, trace string) (*Brick, *ReplaySerialPort) {
+	t.Helper()
+	replay, err := NewReplaySerialPort(strings.NewReader(trace))
+	if err != nil {
+		t.Fatalf("NewReplaySerialPort failed: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewBrick(replay, replay, logger), replay
+}
+
+func waitReplayDone(t *testing.T, replay *ReplaySerialPort) {
+	t.Helper()
+	select {
+	case <-replay.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the replay to finish")
+	}
+	time.Sleep(20 * time.Millisecond) // Let the reader parse the last line
+}
+
+func TestReplaySerialPort_CapturedSession(t *testing.T) {
+	trace := `10:00:00.000000 TX> list
+10:00:00.010000 RX< P0: connected to active ID 4B
+10:00:00.010500 RX< P1: connected to passive ID 1
+10:00:00.011000 RX< P2: no device detected
+10:00:00.060000 RX< P0C0: 0 90 45
+`
+	brick, replay := newReplayBrick(t, trace)
+	defer CleanupTestBrick(brick)
+
+	start := time.Now()
+	waitReplayDone(t, replay)
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected playback to follow the recorded timing (60ms), took %v", elapsed)
+	}
+
+	if typeID, connected := brick.DeviceType(PortA); typeID != 0x4B || !connected {
+		t.Errorf("Expected motor 0x4B on port A, got %d %v", typeID, connected)
+	}
+	if typeID, connected := brick.DeviceType(PortB); typeID != 1 || !connected {
+		t.Errorf("Expected passive device 1 on port B, got %d %v", typeID, connected)
+	}
+	if _, connected := brick.DeviceType(PortC); connected {
+		t.Error("Expected no device on port C")
+	}
+	if data, ok := brick.LatestSensorData(PortA); !ok || len(data) != 3 || data[1] != 90 {
+		t.Errorf("Expected the replayed sample on port A, got %v", data)
+	}
+
+	// Commands the brick sends are recorded, not played back
+	if err := brick.SendRawCommand("list"); err != nil {
+		t.Fatalf("SendRawCommand failed: %v", err)
+	}
+	if history := replay.GetWriteHistory(); len(history) != 1 || history[0] != "list\r" {
+		t.Errorf("Expected the write to be recorded, got %q", history)
+	}
+}
+
+func TestReplaySerialPort_TracerRoundTrip(t *testing.T) {
+	brick := TestBrick(t)
+	var trace bytes.Buffer
+	brick.SetTracer(&trace)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P3: connected to active ID 3D\r\n")
+	mockPort.SimulateSensorResponse("D", 5, "512 256 768 1024")
+	time.Sleep(50 * time.Millisecond)
+	CleanupTestBrick(brick)
+
+	replayed, replay := newReplayBrick(t, trace.String())
+	defer CleanupTestBrick(replayed)
+	waitReplayDone(t, replay)
+
+	if typeID, connected := replayed.DeviceType(PortD); typeID != 0x3D || !connected {
+		t.Errorf("Expected color sensor on port D after replay, got %d %v", typeID, connected)
+	}
+	if data, ok := replayed.LatestSensorData(PortD); !ok || len(data) != 4 || data[0] != 512 {
+		t.Errorf("Expected replayed reading on port D, got %v", data)
+	}
+}
+
+func TestNewReplaySerialPort_InvalidTrace(t *testing.T) {
+	for _, trace := range []string{
+		"not a trace line",
+		"10:00:00.000000 ?? P0: disconnected",
+		"yesterday RX< P0: disconnected",
+	} {
+		if _, err := NewReplaySerialPort(strings.NewReader(trace)); err == nil {
+			t.Errorf("Expected error for trace %q", trace)
+		}
+	}
+
+	// An empty trace is done straight away
+	replay, err := NewReplaySerialPort(strings.NewReader("\n"))
+	if err != nil {
+		t.Fatalf("NewReplaySerialPort failed: %v", err)
+	}
+	select {
+	case <-replay.Done():
+	default:
+		t.Error("Expected an empty trace to be done")
+	}
+}
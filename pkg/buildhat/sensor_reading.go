@@ -0,0 +1,223 @@
+package buildhat
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SensorReading is a single parsed data message from a port.
+// RawBytes is set when the payload is raw hex output (a mode selected without
+// offset/format). Lines made only of two-digit decimal tokens are ambiguous and
+// populate both RawBytes and Values. A token that isn't a number is kept as NaN,
+// which is encoded as null in JSON along with ±Inf.
+// When the mode was selected with a DataFormat, Values are decoded with that
+// format's width and signedness and Format is set.
+type SensorReading struct {
+	Port     Port       `json:"port"`                // Port the reading came from
+	Mode     int        `json:"mode"`                // Mode (or combi index when Combi is true) reported in the prefix
+	Combi    bool       `json:"combi"`               // True for combi data ("P0C0:"), false for mode data ("P0M0:")
+	Values   []float64  `json:"values,omitempty"`    // Parsed numeric values, in order
+	RawBytes []byte     `json:"raw_bytes,omitempty"` // Decoded payload for raw hex output
+	Format   DataFormat `json:"format,omitempty"`    // Data format the mode was selected with, if any
+	Raw      string     `json:"raw"`                 // Full line as received
+	At       time.Time  `json:"at"`                  // Time the reading was parsed
+
+	// data keeps the legacy mixed int/float representation used by getSensorData
+	data []any
+}
+
+// MarshalJSON encodes the reading, writing NaN and ±Inf values as null since JSON has no
+// representation for them
+func (r SensorReading) MarshalJSON() ([]byte, error) {
+	type plain SensorReading
+	values := make([]*float64, len(r.Values))
+	for i := range r.Values {
+		if !math.IsNaN(r.Values[i]) && !math.IsInf(r.Values[i], 0) {
+			values[i] = &r.Values[i]
+		}
+	}
+	return json.Marshal(struct {
+		plain
+		Values []*float64 `json:"values,omitempty"`
+	}{plain(r), values})
+}
+
+// parseSensorReading parses a sensor data line such as "P0M1: 123 456" or "P2C0: 1.5 2.3"
+func parseSensorReading(port Port, line string) SensorReading {
+	reading := SensorReading{
+		Port: port,
+		Mode: -1,
+		Raw:  line,
+		At:   time.Now(),
+	}
+
+	if len(line) < 4 {
+		return reading
+	}
+
+	reading.Combi = line[2] == 'C'
+	modeStr, payload, _ := strings.Cut(line[3:], ":")
+	if mode, err := strconv.Atoi(modeStr); err == nil {
+		reading.Mode = mode
+	}
+
+	parts := strings.Fields(payload)
+	reading.Values = make([]float64, 0, len(parts))
+	reading.data = make([]any, 0, len(parts))
+
+	if raw, ok := decodeHexTokens(parts); ok {
+		reading.RawBytes = raw
+		// Hex letters mean the tokens cannot be decimal values
+		if strings.ContainsAny(payload, "abcdefABCDEF") {
+			return reading
+		}
+	}
+
+	for _, part := range parts {
+		value, integer := parseSensorValue(part)
+		reading.Values = append(reading.Values, value)
+		if integer {
+			reading.data = append(reading.data, int(value))
+		} else {
+			reading.data = append(reading.data, value)
+		}
+	}
+
+	return reading
+}
+
+// parseSensorValue parses one data token. Integer tokens are reported as such so the legacy
+// representation keeps them as int; anything else ParseFloat accepts, including "1e-3",
+// "inf" and "nan", is a float. Unparseable tokens become NaN rather than being dropped,
+// which would shift the index of every later value.
+func parseSensorValue(token string) (float64, bool) {
+	if val, err := strconv.ParseInt(token, 10, 32); err == nil {
+		return float64(val), true
+	}
+	if val, err := strconv.ParseFloat(token, 64); err == nil {
+		return val, false
+	}
+	return math.NaN(), false
+}
+
+// applyFormat reinterprets the reading's values as the given data format. Raw hex payloads
+// are decoded as consecutive little-endian values; decimal integers are wrapped to the
+// format's width, so an s1 reading of 255 becomes -1.
+func (r *SensorReading) applyFormat(format DataFormat) {
+	size, ok := dataFormatSizes[format]
+	if !ok {
+		return
+	}
+	r.Format = format
+
+	if len(r.Values) == 0 && len(r.RawBytes) > 0 {
+		for offset := 0; offset+size <= len(r.RawBytes); offset += size {
+			value, _ := decodeDataFormat(r.RawBytes[offset:], format)
+			r.Values = append(r.Values, value)
+		}
+	} else {
+		for i, value := range r.Values {
+			r.Values[i] = wrapDataFormat(value, format)
+		}
+	}
+
+	r.data = make([]any, len(r.Values))
+	for i, value := range r.Values {
+		if isIntegral(value) && format != DataFormatF4 {
+			r.data[i] = int(value)
+		} else {
+			r.data[i] = value
+		}
+	}
+}
+
+// wrapDataFormat wraps an integer value to the width and signedness of an integer format.
+// Floats, NaN and f4 values are returned unchanged.
+func wrapDataFormat(value float64, format DataFormat) float64 {
+	if !isIntegral(value) {
+		return value
+	}
+
+	n := int64(value)
+	switch format {
+	case DataFormatU1:
+		return float64(uint8(n))
+	case DataFormatS1:
+		return float64(int8(n))
+	case DataFormatU2:
+		return float64(uint16(n))
+	case DataFormatS2:
+		return float64(int16(n))
+	case DataFormatU4:
+		return float64(uint32(n))
+	case DataFormatS4:
+		return float64(int32(n))
+	}
+	return value
+}
+
+// isIntegral reports whether value is a finite whole number
+func isIntegral(value float64) bool {
+	return !math.IsInf(value, 0) && value == math.Trunc(value)
+}
+
+// dataFormatSizes holds the width in bytes of each data format
+var dataFormatSizes = map[DataFormat]int{
+	DataFormatU1: 1, DataFormatS1: 1,
+	DataFormatU2: 2, DataFormatS2: 2,
+	DataFormatU4: 4, DataFormatS4: 4, DataFormatF4: 4,
+}
+
+// decodeDataFormat decodes a little-endian value of the given format from the start of raw
+func decodeDataFormat(raw []byte, format DataFormat) (float64, error) {
+	size, ok := dataFormatSizes[format]
+	if !ok {
+		return 0, fmt.Errorf("invalid data format: %q", format)
+	}
+	if len(raw) < size {
+		return 0, fmt.Errorf("%s value needs %d bytes, got %d", format, size, len(raw))
+	}
+
+	switch format {
+	case DataFormatU1:
+		return float64(raw[0]), nil
+	case DataFormatS1:
+		return float64(int8(raw[0])), nil
+	case DataFormatU2:
+		return float64(binary.LittleEndian.Uint16(raw)), nil
+	case DataFormatS2:
+		return float64(int16(binary.LittleEndian.Uint16(raw))), nil
+	case DataFormatU4:
+		return float64(binary.LittleEndian.Uint32(raw)), nil
+	case DataFormatS4:
+		return float64(int32(binary.LittleEndian.Uint32(raw))), nil
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))), nil
+	}
+}
+
+// decodeHexTokens decodes tokens that are all two-digit hex bytes, e.g. "1a 2b ff"
+func decodeHexTokens(tokens []string) ([]byte, bool) {
+	if len(tokens) == 0 {
+		return nil, false
+	}
+
+	raw := make([]byte, 0, len(tokens))
+	for _, token := range tokens {
+		if len(token) != 2 {
+			return nil, false
+		}
+		decoded, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, false
+		}
+		raw = append(raw, decoded[0])
+	}
+	return raw, true
+}
@@ -0,0 +1,237 @@
+package buildhat
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSensorReading(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   Port
+		line   string
+		mode   int
+		combi  bool
+		values []float64
+	}{
+		{"mode data", PortA, "P0M1: 123 456", 1, false, []float64{123, 456}},
+		{"combi data", PortC, "P2C0: 1.5 2.3", 0, true, []float64{1.5, 2.3}},
+		{"two digit mode", PortB, "P1M10: 7", 10, false, []float64{7}},
+		{"negative values", PortD, "P3C0: -20 -359 180", 0, true, []float64{-20, -359, 180}},
+		{"no values", PortA, "P0M0:", 0, false, []float64{}},
+		{"scientific notation", PortB, "P1M0: 1e-3 1.5e2 -2E1", 0, false, []float64{0.001, 150, -20}},
+		{"infinity", PortC, "P2M2: 5 inf -Inf", 2, false, []float64{5, math.Inf(1), math.Inf(-1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := parseSensorReading(tt.port, tt.line)
+			if reading.Port != tt.port {
+				t.Errorf("Expected port %s, got %s", tt.port, reading.Port)
+			}
+			if reading.Mode != tt.mode {
+				t.Errorf("Expected mode %d, got %d", tt.mode, reading.Mode)
+			}
+			if reading.Combi != tt.combi {
+				t.Errorf("Expected combi %v, got %v", tt.combi, reading.Combi)
+			}
+			if !slices.Equal(reading.Values, tt.values) {
+				t.Errorf("Expected values %v, got %v", tt.values, reading.Values)
+			}
+			if reading.Raw != tt.line {
+				t.Errorf("Expected raw %q, got %q", tt.line, reading.Raw)
+			}
+			if reading.At.IsZero() {
+				t.Error("Expected timestamp to be set")
+			}
+		})
+	}
+}
+
+func TestParseSensorReading_LegacyData(t *testing.T) {
+	reading := parseSensorReading(PortA, "P0C0: 10 1.5")
+
+	if len(reading.data) != 2 {
+		t.Fatalf("Expected 2 legacy values, got %d", len(reading.data))
+	}
+	if v, ok := reading.data[0].(int); !ok || v != 10 {
+		t.Errorf("Expected int 10, got %v (%T)", reading.data[0], reading.data[0])
+	}
+	if v, ok := reading.data[1].(float64); !ok || v != 1.5 {
+		t.Errorf("Expected float64 1.5, got %v (%T)", reading.data[1], reading.data[1])
+	}
+}
+
+func TestParseSensorReading_UnparseableToken(t *testing.T) {
+	reading := parseSensorReading(PortA, "P0C0: 10 ?? 2.5e1 -7")
+
+	if len(reading.Values) != 4 {
+		t.Fatalf("Expected the garbage token to keep its position, got %v", reading.Values)
+	}
+	if !math.IsNaN(reading.Values[1]) {
+		t.Errorf("Expected NaN for the garbage token, got %v", reading.Values[1])
+	}
+	if reading.Values[0] != 10 || reading.Values[2] != 25 || reading.Values[3] != -7 {
+		t.Errorf("Expected later values at their index, got %v", reading.Values)
+	}
+
+	// The legacy form keeps integers as int and the rest as float64
+	if _, ok := reading.data[0].(int); !ok {
+		t.Errorf("Expected int for 10, got %T", reading.data[0])
+	}
+	if v, ok := reading.data[2].(float64); !ok || v != 25 {
+		t.Errorf("Expected float64 25 for 2.5e1, got %v (%T)", reading.data[2], reading.data[2])
+	}
+	if v, ok := reading.data[3].(int); !ok || v != -7 {
+		t.Errorf("Expected int -7, got %v (%T)", reading.data[3], reading.data[3])
+	}
+}
+
+func TestBrick_GetSensorReading(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.SimulateSensorResponse("B", 5, "100 200 300 400")
+
+	reading, err := brick.GetSensorReading(PortB)
+	if err != nil {
+		t.Fatalf("GetSensorReading failed: %v", err)
+	}
+
+	if reading.Port != PortB || reading.Mode != 5 || reading.Combi {
+		t.Errorf("Unexpected reading header: port=%s mode=%d combi=%v", reading.Port, reading.Mode, reading.Combi)
+	}
+	if !slices.Equal(reading.Values, []float64{100, 200, 300, 400}) {
+		t.Errorf("Unexpected values: %v", reading.Values)
+	}
+	if time.Since(reading.At) > time.Minute {
+		t.Errorf("Unexpected timestamp: %v", reading.At)
+	}
+}
+
+func TestBrick_GetSensorReading_InvalidPort(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if _, err := brick.GetSensorReading(Port(9)); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+func TestParseSensorReading_RawHex(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		rawBytes []byte
+		values   []float64
+	}{
+		{"hex with letters", "P0M0: 1a 2b ff", []byte{0x1a, 0x2b, 0xff}, []float64{}},
+		{"uppercase hex", "P0M0: 1A 2B FF", []byte{0x1a, 0x2b, 0xff}, []float64{}},
+		{"ambiguous two digit tokens", "P0M0: 10 20", []byte{0x10, 0x20}, []float64{10, 20}},
+		{"decimal values", "P0M0: 123 4567", nil, []float64{123, 4567}},
+		{"float values", "P0C0: 1.5 2.3", nil, []float64{1.5, 2.3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := parseSensorReading(PortA, tt.line)
+			if !slices.Equal(reading.RawBytes, tt.rawBytes) {
+				t.Errorf("Expected raw bytes %x, got %x", tt.rawBytes, reading.RawBytes)
+			}
+			if !slices.Equal(reading.Values, tt.values) {
+				t.Errorf("Expected values %v, got %v", tt.values, reading.Values)
+			}
+		})
+	}
+}
+
+func TestBrick_GetSensorReading_RawHex(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	brick.GetMockPort().QueueReadData("P0M0: 1a 2b ff\r\n")
+
+	reading, err := brick.GetSensorReading(PortA)
+	if err != nil {
+		t.Fatalf("GetSensorReading failed: %v", err)
+	}
+	if !slices.Equal(reading.RawBytes, []byte{0x1a, 0x2b, 0xff}) {
+		t.Errorf("Expected raw bytes 1a2bff, got %x", reading.RawBytes)
+	}
+}
+
+func TestSensorReading_ApplyFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		format DataFormat
+		values []float64
+	}{
+		{"u1 boundary", "P0M0: 0 255", DataFormatU1, []float64{0, 255}},
+		{"u1 wraps negative", "P0M0: -1", DataFormatU1, []float64{255}},
+		{"s1 wraps 255", "P0M0: 255 127 128", DataFormatS1, []float64{-1, 127, -128}},
+		{"s1 raw hex", "P0M0: ff 7f 80", DataFormatS1, []float64{-1, 127, -128}},
+		{"u2 boundary", "P0M0: 65535 -1", DataFormatU2, []float64{65535, 65535}},
+		{"u2 raw hex", "P0M0: ff ff 2c 01", DataFormatU2, []float64{65535, 300}},
+		{"s2 wraps 65535", "P0M0: 65535 32768 32767", DataFormatS2, []float64{-1, -32768, 32767}},
+		{"s2 raw hex", "P0M0: d4 fe", DataFormatS2, []float64{-300}},
+		{"u4 boundary", "P0M0: 4294967295 -1", DataFormatU4, []float64{4294967295, 4294967295}},
+		{"s4 wraps", "P0M0: 4294967295 2147483648", DataFormatS4, []float64{-1, -2147483648}},
+		{"s4 raw hex", "P0M0: ff ff ff ff", DataFormatS4, []float64{-1}},
+		{"f4 decimal", "P0M0: 1.5 -2", DataFormatF4, []float64{1.5, -2}},
+		{"f4 raw hex", "P0M0: 00 00 c0 3f", DataFormatF4, []float64{1.5}},
+		{"float kept under integer format", "P0M0: 1.5", DataFormatS1, []float64{1.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := parseSensorReading(PortA, tt.line)
+			reading.applyFormat(tt.format)
+			if !slices.Equal(reading.Values, tt.values) {
+				t.Errorf("Expected values %v, got %v", tt.values, reading.Values)
+			}
+			if reading.Format != tt.format {
+				t.Errorf("Expected format %q, got %q", tt.format, reading.Format)
+			}
+			for i, value := range reading.data {
+				_, isInt := value.(int)
+				if wantInt := tt.format != DataFormatF4 && tt.values[i] == math.Trunc(tt.values[i]); isInt != wantInt {
+					t.Errorf("Expected data[%d] = %v to be int: %v", i, value, wantInt)
+				}
+			}
+		})
+	}
+}
+
+func TestBrick_SelectedFormatDecodesReadings(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	if err := brick.writeCommand(Compound(SelectPort(PortA), SelectFormatted(1, 0, DataFormatS1))); err != nil {
+		t.Fatalf("writeCommand failed: %v", err)
+	}
+	brick.handleSensorData(PortA, "P0M1: 255")
+	reading, err := brick.GetSensorReading(PortA)
+	if err != nil {
+		t.Fatalf("GetSensorReading failed: %v", err)
+	}
+	if !slices.Equal(reading.Values, []float64{-1}) || reading.Format != DataFormatS1 {
+		t.Errorf("Expected s1 value -1, got %v (%q)", reading.Values, reading.Format)
+	}
+
+	// Another mode, and the same mode once deselected, read as plain values
+	brick.handleSensorData(PortA, "P0M2: 255")
+	if reading, _ := brick.GetSensorReading(PortA); !slices.Equal(reading.Values, []float64{255}) {
+		t.Errorf("Expected unformatted mode 2 value 255, got %v", reading.Values)
+	}
+	if err := brick.writeCommand(Compound(SelectPort(PortA), Select(1))); err != nil {
+		t.Fatalf("writeCommand failed: %v", err)
+	}
+	brick.handleSensorData(PortA, "P0M1: 255")
+	if reading, _ := brick.GetSensorReading(PortA); !slices.Equal(reading.Values, []float64{255}) || reading.Format != "" {
+		t.Errorf("Expected plain value 255 after reselect, got %v (%q)", reading.Values, reading.Format)
+	}
+}
@@ -0,0 +1,44 @@
+package buildhat
+
+import "time"
+
+// Snapshot is a point-in-time copy of the brick's state, suitable for JSON encoding
+type Snapshot struct {
+	Ports           []PortSnapshot `json:"ports"`
+	Voltage         float64        `json:"voltage,omitempty"`          // Last reported input voltage, 0 if never read
+	FirmwareVersion string         `json:"firmware_version,omitempty"` // Last reported firmware version
+	At              time.Time      `json:"at"`
+}
+
+// PortSnapshot is the state of a single port
+type PortSnapshot struct {
+	Device  DeviceInfo     `json:"device"`
+	Reading *SensorReading `json:"reading,omitempty"` // Latest reading, nil if none received
+}
+
+// Snapshot copies the state of every port along with the last voltage and firmware version.
+// It does not query the HAT; values are those most recently received.
+func (b *Brick) Snapshot() Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Ports:           make([]PortSnapshot, 0, NumPorts),
+		Voltage:         b.voltage,
+		FirmwareVersion: b.firmwareVersion,
+		At:              time.Now(),
+	}
+	for _, port := range AllPorts() {
+		ps := PortSnapshot{Device: b.deviceInfo(port)}
+		if latest := b.connections[port].latest; latest != nil {
+			reading := *latest
+			reading.Values = append([]float64(nil), reading.Values...)
+			reading.RawBytes = append([]byte(nil), reading.RawBytes...)
+			reading.data = nil
+			ps.Reading = &reading
+		}
+		snapshot.Ports = append(snapshot.Ports, ps)
+	}
+
+	return snapshot
+}
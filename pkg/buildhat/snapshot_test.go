@@ -0,0 +1,127 @@
+package buildhat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrick_Snapshot_JSON(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 30\r\n")
+	mockPort.QueueReadData("P1: connected to active ID 3D\r\n")
+	mockPort.QueueReadData("P1M5: 512 256 128 900\r\n")
+	mockPort.QueueReadData("Firmware version: 1737564117 2025-01-22T16:41:57+00:00\r\n")
+	mockPort.QueueReadData("8.2 V\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	snapshot := brick.Snapshot()
+	if len(snapshot.Ports) != NumPorts {
+		t.Fatalf("Expected %d ports, got %d", NumPorts, len(snapshot.Ports))
+	}
+	if snapshot.Ports[1].Reading == nil || len(snapshot.Ports[1].Reading.Values) != 4 {
+		t.Fatalf("Expected the sensor reading on port B, got %+v", snapshot.Ports[1].Reading)
+	}
+	if snapshot.Ports[0].Reading != nil {
+		t.Errorf("Expected no reading on port A, got %+v", snapshot.Ports[0].Reading)
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	text := string(encoded)
+	for _, want := range []string{
+		`"port":"A"`,
+		`"category":"Motor"`,
+		`"category":"Sensor"`,
+		`"name":"ColorSensor"`,
+		`"values":[512,256,128,900]`,
+		`"voltage":8.2`,
+		`"firmware_version":"1737564117 2025-01-22T16:41:57+00:00"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %s in snapshot JSON: %s", want, text)
+		}
+	}
+}
+
+func TestBrick_Snapshot_JSONRoundTrip(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 30\r\n")
+	mockPort.QueueReadData("P1: connected to active ID 3D\r\n")
+	mockPort.QueueReadData("P1M5: 512 256 128 900\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	snapshot := brick.Snapshot()
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded Snapshot
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(decoded.Ports) != len(snapshot.Ports) {
+		t.Fatalf("Expected %d ports, got %d", len(snapshot.Ports), len(decoded.Ports))
+	}
+	for i, ps := range decoded.Ports {
+		if ps.Device != snapshot.Ports[i].Device {
+			t.Errorf("Port %d: expected device %+v, got %+v", i, snapshot.Ports[i].Device, ps.Device)
+		}
+	}
+	if reading := decoded.Ports[1].Reading; reading == nil || reading.Port != PortB || len(reading.Values) != 4 {
+		t.Errorf("Expected the port B reading to round trip, got %+v", reading)
+	}
+}
+
+func TestBrick_Snapshot_JSON_NonFiniteValues(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P0: connected to active ID 3D\r\n")
+	mockPort.QueueReadData("P0M0: 12 garbage inf 34\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	encoded, err := json.Marshal(brick.Snapshot())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `"values":[12,null,null,34]`; !strings.Contains(string(encoded), want) {
+		t.Errorf("Expected %s in snapshot JSON: %s", want, encoded)
+	}
+}
+
+func TestBrick_Snapshot_IsACopy(t *testing.T) {
+	brick := TestBrick(t)
+	defer CleanupTestBrick(brick)
+
+	mockPort := brick.GetMockPort()
+	mockPort.QueueReadData("P2: connected to active ID 3E\r\n")
+	mockPort.QueueReadData("P2M0: 100\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	snapshot := brick.Snapshot()
+	snapshot.Ports[2].Reading.Values[0] = -1
+
+	if again := brick.Snapshot(); again.Ports[2].Reading.Values[0] != 100 {
+		t.Errorf("Expected the brick's reading to be unaffected, got %v", again.Ports[2].Reading.Values)
+	}
+
+	// A reading consumed by a read is still reported
+	if _, err := brick.GetSensorReading(PortC); err != nil {
+		t.Fatalf("GetSensorReading failed: %v", err)
+	}
+	if again := brick.Snapshot(); again.Ports[2].Reading == nil {
+		t.Error("Expected the latest reading to survive a read")
+	}
+}
@@ -35,10 +35,12 @@ func (d TiltDirection) String() string {
 
 // TiltSensor creates a tilt sensor interface for the specified port
 func (b *Brick) TiltSensor(port Port) *TiltSensor {
-	return &TiltSensor{
-		brick: b,
-		port:  port,
-	}
+	return cachedDevice(b, port, func() *TiltSensor {
+		return &TiltSensor{
+			brick: b,
+			port:  port,
+		}
+	})
 }
 
 // TiltSensor provides a Python-like tilt sensor interface (WeDo sensor)